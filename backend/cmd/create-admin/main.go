@@ -0,0 +1,56 @@
+// Command create-admin bootstraps the first admin account.
+//
+// Usage:
+//
+//	go run ./cmd/create-admin -phone 9876543210 [-name "Ops Lead"]
+//
+// The user is created if the phone number has never logged in, and is then
+// given the admin role. Later admins can be promoted through the admin API.
+package main
+
+import (
+	"flag"
+	"log"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"meesho-clone/internal/pii"
+	"meesho-clone/internal/services"
+	"regexp"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	phone := flag.String("phone", "", "10-digit mobile number of the admin")
+	name := flag.String("name", "", "display name to set on the account")
+	flag.Parse()
+
+	if !regexp.MustCompile(`^[6-9][0-9]{9}$`).MatchString(*phone) {
+		log.Fatal("A valid 10-digit Indian mobile number is required (-phone)")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using default values")
+	}
+	configs.ConnectDatabase()
+
+	userService := services.NewUserService()
+	user, err := userService.CreateOrGetUser(*phone)
+	if err != nil {
+		log.Fatal("Failed to create user:", err)
+	}
+
+	if *name != "" {
+		user, err = userService.UpdateProfile(user.UserID, &models.UpdateProfileRequest{Name: name}, "system:create-admin")
+		if err != nil {
+			log.Fatal("Failed to set name:", err)
+		}
+	}
+
+	user, err = userService.SetRole(user.UserID, models.RoleAdmin)
+	if err != nil {
+		log.Fatal("Failed to grant admin role:", err)
+	}
+
+	log.Printf("User %s (%s) is now an admin", user.UserID, pii.MaskPhone(user.PhoneNumber))
+}
@@ -0,0 +1,178 @@
+// Command encrypt-phones encrypts phone numbers stored in plaintext before
+// encryption at rest was enabled, and fills in the blind index used for lookups.
+// It covers user numbers, their change history, address phones and the
+// shipping phones copied onto orders.
+//
+// Usage:
+//
+//	go run ./cmd/encrypt-phones [-batch 500]
+//
+// It is safe to run repeatedly; rows that are already encrypted are skipped.
+// The old idx_phone_number index is dropped because uniqueness is now
+// enforced on the blind index.
+package main
+
+import (
+	"flag"
+	"log"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"meesho-clone/internal/pii"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 500, "rows to migrate per batch")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using default values")
+	}
+	configs.ConnectDatabase()
+	db := configs.DB
+
+	if db.Migrator().HasIndex(&models.User{}, "idx_phone_number") {
+		if err := db.Migrator().DropIndex(&models.User{}, "idx_phone_number"); err != nil {
+			log.Fatal("Failed to drop idx_phone_number:", err)
+		}
+		log.Println("Dropped idx_phone_number")
+	}
+
+	type userRow struct {
+		ID          uint
+		PhoneNumber string
+	}
+
+	// Raw table access bypasses the encrypted serializer and soft-delete scope,
+	// so every row is migrated and values are read exactly as stored
+	var migrated, lastID uint
+	for {
+		var rows []userRow
+		err := db.Table("users").
+			Select("id, phone_number").
+			Where("phone_hash IS NULL AND anonymised_at IS NULL AND id > ?", lastID).
+			Order("id").
+			Limit(*batchSize).
+			Find(&rows).Error
+		if err != nil {
+			log.Fatal("Failed to read users:", err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		for _, row := range rows {
+			lastID = row.ID
+
+			phoneNumber, err := pii.Decrypt(row.PhoneNumber)
+			if err != nil {
+				log.Printf("Warning: Skipping user row %d: %v", row.ID, err)
+				continue
+			}
+			encrypted, err := pii.Encrypt(phoneNumber)
+			if err != nil {
+				log.Fatal("Failed to encrypt phone number:", err)
+			}
+
+			err = db.Table("users").Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"phone_number": encrypted,
+				"phone_hash":   pii.BlindIndex(phoneNumber),
+			}).Error
+			if err != nil {
+				log.Printf("Warning: Failed to migrate user row %d: %v", row.ID, err)
+				continue
+			}
+			migrated++
+		}
+	}
+	log.Printf("Encrypted %d user phone numbers", migrated)
+
+	type historyRow struct {
+		ID          uint
+		PhoneNumber string
+		ReplacedBy  string
+	}
+
+	var history []historyRow
+	err := db.Table("phone_number_history").
+		Select("id, phone_number, replaced_by").
+		Find(&history).Error
+	if err != nil {
+		log.Fatal("Failed to read phone number history:", err)
+	}
+
+	var historyMigrated int
+	for _, row := range history {
+		updates := map[string]interface{}{}
+		for column, value := range map[string]string{"phone_number": row.PhoneNumber, "replaced_by": row.ReplacedBy} {
+			if pii.IsEncrypted(value) {
+				continue
+			}
+			encrypted, err := pii.Encrypt(value)
+			if err != nil {
+				log.Fatal("Failed to encrypt phone number:", err)
+			}
+			updates[column] = encrypted
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := db.Table("phone_number_history").Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+			log.Printf("Warning: Failed to migrate history row %d: %v", row.ID, err)
+			continue
+		}
+		historyMigrated++
+	}
+	log.Printf("Encrypted %d phone number history rows", historyMigrated)
+
+	for _, target := range []struct{ table, column string }{
+		{"addresses", "phone"},
+		{"orders", "shipping_phone"},
+	} {
+		encrypted := encryptColumn(db, target.table, target.column, *batchSize)
+		log.Printf("Encrypted %d %s.%s values", encrypted, target.table, target.column)
+	}
+}
+
+// encryptColumn encrypts the plaintext values left in a column and returns
+// how many rows were updated. Empty values are left as they are.
+func encryptColumn(db *gorm.DB, table, column string, batchSize int) int {
+	type row struct {
+		ID    uint
+		Value string
+	}
+
+	var migrated int
+	var lastID uint
+	for {
+		var rows []row
+		err := db.Table(table).
+			Select("id, "+column+" AS value").
+			Where(column+" <> '' AND "+column+" NOT LIKE 'v1:%' AND id > ?", lastID).
+			Order("id").
+			Limit(batchSize).
+			Find(&rows).Error
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", table, err)
+		}
+		if len(rows) == 0 {
+			return migrated
+		}
+
+		for _, r := range rows {
+			lastID = r.ID
+
+			encrypted, err := pii.Encrypt(r.Value)
+			if err != nil {
+				log.Fatal("Failed to encrypt phone number:", err)
+			}
+			if err := db.Table(table).Where("id = ?", r.ID).Update(column, encrypted).Error; err != nil {
+				log.Printf("Warning: Failed to migrate %s row %d: %v", table, r.ID, err)
+				continue
+			}
+			migrated++
+		}
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"meesho-clone/configs"
 	"meesho-clone/internal/handlers"
 	"meesho-clone/internal/middleware"
+	"meesho-clone/internal/models"
 	"meesho-clone/internal/services"
 	"time"
 
@@ -22,12 +24,23 @@ func main() {
 	// Connect to database
 	configs.ConnectDatabase()
 
+	// Refuse to start without a usable online payment gateway
+	if err := services.CheckPaymentConfig(); err != nil {
+		log.Fatal("Invalid payment configuration: ", err)
+	}
+
 	// Set Gin mode (release for production)
 	gin.SetMode(gin.DebugMode) // Change to gin.ReleaseMode for production
 
 	// Create Gin router
 	router := gin.Default()
 
+	// Client IPs key the rate limits and OTP throttles, so X-Forwarded-For is
+	// only honoured when it comes from one of our own proxies
+	if err := router.SetTrustedProxies(configs.TrustedProxies()); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES: ", err)
+	}
+
 	// Add middleware
 	router.Use(middleware.CORSMiddleware())
 	router.Use(middleware.LoggerMiddleware())
@@ -36,13 +49,76 @@ func main() {
 	// Initialize services
 	userService := services.NewUserService()
 	productService := services.NewProductService()
+	outboxService := services.NewOutboxService()
+	reservationService := services.NewReservationService()
+	tokenService, err := services.NewTokenService()
+	if err != nil {
+		log.Fatal("Invalid token configuration: ", err)
+	}
+	sessionService := services.NewSessionService()
+	accountService := services.NewAccountService()
+	rtoAgeService := services.NewRTOAgeService()
+	trendingService := services.NewTrendingService()
+	paymentService := services.NewPaymentService()
+	idempotencyService := services.NewIdempotencyService()
+	smsSender, err := services.NewSMSSender()
+	if err != nil {
+		log.Fatal("Invalid SMS configuration: ", err)
+	}
+	otpService, err := services.NewOTPService(smsSender)
+	if err != nil {
+		log.Fatal("Invalid OTP configuration: ", err)
+	}
+
+	// Start background workers
+	go outboxService.Start(context.Background())
+	go reservationService.StartExpirySweeper(context.Background())
+	go accountService.StartAnonymiser(context.Background())
+	go rtoAgeService.StartExpirySweeper(context.Background())
+	go trendingService.StartTrendingJob(context.Background())
+	go paymentService.StartPaymentTimeoutSweeper(context.Background())
+	go otpService.StartOTPPurger(context.Background())
+	go idempotencyService.StartExpirySweeper(context.Background())
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler()
+	authHandler := handlers.NewAuthHandler(tokenService, otpService)
 	homescreenHandler := handlers.NewHomescreenHandler()
 	catalogHandler := handlers.NewCatalogHandler()
 	productHandler := handlers.NewProductHandler(productService, userService)
 	orderHandler := handlers.NewOrderHandler()
+	cartHandler := handlers.NewCartHandler()
+	addressHandler := handlers.NewAddressHandler()
+	paymentHandler := handlers.NewPaymentHandler()
+	adminHandler := handlers.NewAdminHandler(outboxService)
+
+	// Routes that act on behalf of a user require a bearer access token
+	requireAuth := middleware.RequireAuth(tokenService, sessionService, userService)
+	optionalAuth := middleware.OptionalAuth(tokenService, sessionService, userService)
+
+	// Rate limits are kept in memory; a middleware.RedisRateLimitStore shares
+	// them when running more than one instance
+	rateLimitStore := middleware.NewMemoryRateLimitStore()
+	loginLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitPolicy{Name: "login_ip", Limit: 10, Period: time.Minute, Key: middleware.KeyByIP},
+	)
+	otpRequestLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitPolicy{Name: "otp_request_ip", Limit: 10, Period: time.Minute, Key: middleware.KeyByIP},
+		middleware.RateLimitPolicy{Name: "otp_request_phone", Limit: 3, Period: 5 * time.Minute, Key: middleware.KeyByPhone},
+	)
+	otpVerifyLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitPolicy{Name: "otp_verify_ip", Limit: 20, Period: time.Minute, Key: middleware.KeyByIP},
+		middleware.RateLimitPolicy{Name: "otp_verify_phone", Limit: 10, Period: 5 * time.Minute, Key: middleware.KeyByPhone},
+	)
+	validateLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitPolicy{Name: "validate_ip", Limit: 10, Period: time.Minute, Key: middleware.KeyByIP},
+		middleware.RateLimitPolicy{Name: "validate_phone", Limit: 5, Period: time.Minute, Key: middleware.KeyByPhone},
+	)
+	placeOrderLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitPolicy{Name: "order_place_user", Limit: 10, Period: time.Minute, Key: middleware.KeyByUser},
+	)
+	catalogLimit := middleware.RateLimit(rateLimitStore,
+		middleware.RateLimitPolicy{Name: "catalog_user", Limit: 60, Period: time.Minute, Key: middleware.KeyByUser},
+	)
 
 	// Health check endpoint
 	router.GET("/health", authHandler.HealthCheck)
@@ -53,14 +129,30 @@ func main() {
 		// Auth routes
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/login", authHandler.Login)
-			auth.GET("/validate", authHandler.ValidateUser)
-			auth.GET("/profile/:user_id", authHandler.GetUserProfile)
-			auth.PUT("/profile/:user_id", authHandler.UpdateUserProfile)
+			auth.POST("/login", loginLimit, authHandler.Login)
+			auth.POST("/otp/request", otpRequestLimit, authHandler.RequestOTP)
+			auth.POST("/otp/verify", otpVerifyLimit, authHandler.VerifyOTP)
+			auth.POST("/token/refresh", authHandler.RefreshToken)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/phone/change/request", requireAuth, authHandler.RequestPhoneChange)
+			auth.POST("/phone/change/verify", requireAuth, authHandler.ConfirmPhoneChange)
+			auth.GET("/sessions", requireAuth, authHandler.ListSessions)
+			auth.DELETE("/sessions", requireAuth, authHandler.RevokeAllSessions)
+			auth.DELETE("/sessions/:session_id", requireAuth, authHandler.RevokeSession)
+			auth.GET("/validate", validateLimit, optionalAuth, authHandler.ValidateUser)
+			auth.GET("/profile/:user_id", requireAuth, authHandler.GetUserProfile)
+			auth.PUT("/profile/:user_id", requireAuth, authHandler.UpdateUserProfile)
+			auth.DELETE("/profile/:user_id", requireAuth, authHandler.DeleteAccount)
+			auth.GET("/profile/:user_id/export", requireAuth, authHandler.ExportUserData)
+			auth.GET("/profile/:user_id/addresses", requireAuth, addressHandler.ListAddresses)
+			auth.POST("/profile/:user_id/addresses", requireAuth, addressHandler.CreateAddress)
+			auth.PUT("/profile/:user_id/addresses/:address_id", requireAuth, addressHandler.UpdateAddress)
+			auth.DELETE("/profile/:user_id/addresses/:address_id", requireAuth, addressHandler.DeleteAddress)
+			auth.POST("/profile/:user_id/addresses/:address_id/default", requireAuth, addressHandler.SetDefaultAddress)
 		}
 
 		// Homescreen routes
-		home := v1.Group("/home")
+		home := v1.Group("/home", requireAuth)
 		{
 			home.POST("/", homescreenHandler.GetHomescreen)
 			home.GET("/", homescreenHandler.GetHomescreen) // Support both GET and POST
@@ -70,7 +162,7 @@ func main() {
 		}
 
 		// Search and product routes
-		products := v1.Group("/products")
+		products := v1.Group("/products", requireAuth)
 		{
 			products.GET("/search", homescreenHandler.SearchProducts)
 			products.GET("/:product_id", homescreenHandler.GetProductDetails)
@@ -79,23 +171,66 @@ func main() {
 		// Product details routes
 		product := v1.Group("/product")
 		{
-			product.GET("/details", productHandler.GetProductDetails)
-			product.GET("/:id", productHandler.GetProductDetailsByID)
+			product.GET("/details", requireAuth, productHandler.GetProductDetails)
+			product.GET("/:id", requireAuth, productHandler.GetProductDetailsByID)
 			product.GET("/health", productHandler.HealthCheck)
 		}
 
 		// Catalog routes
 		catalog := v1.Group("/catalog")
 		{
-			catalog.GET("/", catalogHandler.GetCatalogData) // Single API for banner widget click
+			catalog.GET("/", requireAuth, catalogLimit, catalogHandler.GetCatalogData) // Single API for banner widget click
+			catalog.POST("/click", requireAuth, catalogLimit, catalogHandler.RecordCatalogClick)
 			catalog.GET("/health", catalogHandler.HealthCheck)
 		}
 
 		// Order routes
 		order := v1.Group("/order")
 		{
-			order.POST("/place", orderHandler.PlaceOrder)
+			order.POST("/place", requireAuth, placeOrderLimit, orderHandler.PlaceOrder)
+			order.POST("/reserve", requireAuth, orderHandler.ReserveItem)
+			order.DELETE("/reserve/:reservation_id", requireAuth, orderHandler.ReleaseReservation)
 			order.GET("/health", orderHandler.HealthCheck)
+			order.GET("/user/:user_id", requireAuth, orderHandler.GetUserOrders)
+			order.GET("/:order_id", requireAuth, orderHandler.GetOrder)
+			order.GET("/:order_id/history", requireAuth, orderHandler.GetOrderHistory)
+			order.GET("/:order_id/invoice", requireAuth, orderHandler.GetInvoice)
+			order.POST("/:order_id/cancel", requireAuth, orderHandler.CancelOrder)
+		}
+
+		// Cart routes
+		cart := v1.Group("/cart", requireAuth)
+		{
+			cart.GET("/", cartHandler.GetCart)
+			cart.POST("/", cartHandler.AddItem)
+			cart.PUT("/:product_id", cartHandler.UpdateItem)
+			cart.DELETE("/:product_id", cartHandler.RemoveItem)
+			cart.POST("/checkout", cartHandler.Checkout)
+		}
+
+		// Payment routes
+		payments := v1.Group("/payments")
+		{
+			payments.POST("/:payment_id/confirm", requireAuth, paymentHandler.ConfirmPayment)
+			payments.POST("/webhook/:provider", paymentHandler.Webhook)
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin", requireAuth)
+		{
+			admin.GET("/outbox", middleware.RequirePermission(models.PermissionOutboxManage), adminHandler.ListOutboxEvents)
+			admin.POST("/outbox/:id/retry", middleware.RequirePermission(models.PermissionOutboxManage), adminHandler.RetryOutboxEvent)
+			admin.POST("/orders/:order_id/status", middleware.RequirePermission(models.PermissionOrdersManage), adminHandler.UpdateOrderStatus)
+			admin.POST("/rto/import", middleware.RequirePermission(models.PermissionRTOManage), adminHandler.ImportRTOManifest)
+			admin.GET("/rto/policies", middleware.RequirePermission(models.PermissionRTOManage), adminHandler.ListRTOAgePolicies)
+			admin.PUT("/rto/policies/:category", middleware.RequirePermission(models.PermissionRTOManage), adminHandler.UpdateRTOAgePolicy)
+			admin.GET("/rto/serviceable", middleware.RequirePermission(models.PermissionRTOManage), adminHandler.ListServiceableRules)
+			admin.PUT("/rto/serviceable/:code", middleware.RequirePermission(models.PermissionRTOManage), adminHandler.SetServiceablePincodes)
+			admin.GET("/catalog/trending", middleware.RequirePermission(models.PermissionWidgetsManage), adminHandler.ListTrendingCatalogs)
+			admin.GET("/catalog/overrides", middleware.RequirePermission(models.PermissionWidgetsManage), adminHandler.ListCatalogOverrides)
+			admin.PUT("/catalog/overrides/:catalog_id", middleware.RequirePermission(models.PermissionWidgetsManage), adminHandler.SetCatalogOverride)
+			admin.DELETE("/catalog/overrides/:catalog_id", middleware.RequirePermission(models.PermissionWidgetsManage), adminHandler.RemoveCatalogOverride)
+			admin.PUT("/users/:user_id/role", middleware.RequirePermission(models.PermissionUsersManage), adminHandler.UpdateUserRole)
 		}
 	}
 
@@ -112,6 +247,9 @@ func main() {
 				"products": "/api/v1/products/*",
 				"catalog":  "/api/v1/catalog/*",
 				"order":    "/api/v1/order/*",
+				"cart":     "/api/v1/cart/*",
+				"payments": "/api/v1/payments/*",
+				"admin":    "/api/v1/admin/*",
 			},
 		})
 	})
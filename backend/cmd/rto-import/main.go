@@ -0,0 +1,72 @@
+// Command rto-import loads courier return manifests into the RTO store.
+//
+// Usage:
+//
+//	go run ./cmd/rto-import -file returns.csv [-format csv|json] [-dry-run] [-report report.json]
+//
+// Rows are validated, deduplicated by sub_order_num and mapped to RTO codes
+// through the serviceable_pincodes rules. The store is chosen by RTO_STORE as
+// for the server. A JSON report of accepted and rejected rows is written to
+// -report, or to stdout when it is not set.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"meesho-clone/configs"
+	"meesho-clone/internal/services"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	file := flag.String("file", "", "path of the manifest to import")
+	format := flag.String("format", "", "manifest format, csv or json (default: from the file extension)")
+	dryRun := flag.Bool("dry-run", false, "validate the manifest without writing to the RTO store")
+	reportPath := flag.String("report", "", "write the JSON report to this file instead of stdout")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("A manifest is required (-file)")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	manifest, err := os.Open(*file)
+	if err != nil {
+		log.Fatal("Failed to open manifest:", err)
+	}
+	defer manifest.Close()
+
+	rows, err := services.ParseRTOManifest(manifest, *format)
+	if err != nil {
+		log.Fatal("Failed to parse manifest:", err)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using default values")
+	}
+	configs.ConnectDatabase()
+
+	report, err := services.NewRTOImportService().Import(filepath.Base(*file), *format, rows, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to import manifest:", err)
+	}
+
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatal("Failed to encode report:", err)
+	}
+	if *reportPath == "" {
+		os.Stdout.Write(append(output, '\n'))
+	} else if err := os.WriteFile(*reportPath, output, 0o644); err != nil {
+		log.Fatal("Failed to write report:", err)
+	}
+
+	log.Printf("Imported %s: %d rows, %d accepted, %d rejected", *file, report.TotalRows, report.AcceptedCount, report.RejectedCount)
+}
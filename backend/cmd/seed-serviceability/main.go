@@ -0,0 +1,86 @@
+// Command seed-serviceability loads the pincodes each RTO code delivers to
+// into serviceable_pincodes. Orders and checkouts are refused for addresses
+// no rule covers, so a fresh deploy needs rules before it can take orders.
+//
+// Usage:
+//
+//	go run ./cmd/seed-serviceability -file serviceable_pincodes.csv
+//
+// The CSV has a code,pincode header and one rule per row; pincode may be a
+// prefix such as 5600. The rules of every code named in the file are
+// replaced by the rows given for it; other codes are left alone.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"io"
+	"log"
+	"meesho-clone/configs"
+	"meesho-clone/internal/services"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	file := flag.String("file", "", "path of the code,pincode CSV to load")
+	flag.Parse()
+
+	if *file == "" {
+		log.Fatal("A rules file is required (-file)")
+	}
+
+	input, err := os.Open(*file)
+	if err != nil {
+		log.Fatal("Failed to open rules file:", err)
+	}
+	defer input.Close()
+
+	reader := csv.NewReader(input)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		log.Fatal("Failed to read header:", err)
+	}
+	if len(header) != 2 || strings.ToLower(header[0]) != "code" || strings.ToLower(header[1]) != "pincode" {
+		log.Fatalf("Expected a code,pincode header, got %v", header)
+	}
+
+	var codes []string
+	pincodes := make(map[string][]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal("Failed to read rules file:", err)
+		}
+		if len(record) != 2 {
+			log.Fatalf("Expected code,pincode, got %v", record)
+		}
+
+		code, pincode := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if _, ok := pincodes[code]; !ok {
+			codes = append(codes, code)
+		}
+		pincodes[code] = append(pincodes[code], pincode)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: .env file not found, using default values")
+	}
+	configs.ConnectDatabase()
+
+	addressService := services.NewAddressService()
+	for _, code := range codes {
+		rules, err := addressService.SetServiceablePincodes(code, pincodes[code])
+		if err != nil {
+			log.Fatalf("Failed to load rules for %s: %v", code, err)
+		}
+		log.Printf("%s now delivers to %d pincodes or prefixes", code, len(rules))
+	}
+}
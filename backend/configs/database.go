@@ -34,6 +34,9 @@ func GetDatabaseConfig() *DatabaseConfig {
 
 // ConnectDatabase establishes database connection
 func ConnectDatabase() {
+	// Encrypted columns cannot be read or written without the keys
+	LoadEncryptionKeys()
+
 	config := GetDatabaseConfig()
 
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
@@ -51,8 +54,41 @@ func ConnectDatabase() {
 
 	DB = database
 
+	if err := dropOTPPhoneNumbers(database); err != nil {
+		log.Fatal("Failed to migrate OTP challenges:", err)
+	}
+
 	// Auto migrate the schema
-	err = database.AutoMigrate(&models.User{}, &models.PriceProductInfo{}, &models.UserMapping{})
+	err = database.AutoMigrate(
+		&models.User{},
+		&models.PriceProductInfo{},
+		&models.UserMapping{},
+		&models.RTOList{},
+		&models.RTOCodeLocation{},
+		&models.CatalogClickEvent{},
+		&models.TrendingCatalog{},
+		&models.CatalogOverride{},
+		&models.RTOAgePolicy{},
+		&models.SupplierReturn{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.OrderStatusHistory{},
+		&models.IdempotencyKey{},
+		&models.OutboxEvent{},
+		&models.InventoryReservation{},
+		&models.CartItem{},
+		&models.Address{},
+		&models.ServiceablePincode{},
+		&models.Payment{},
+		&models.Invoice{},
+		&models.InvoiceSequence{},
+		&models.OTPChallenge{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.ProfileAudit{},
+		&models.PhoneNumberHistory{},
+		&models.UserLookupAudit{},
+	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -60,6 +96,22 @@ func ConnectDatabase() {
 	log.Println("Database connected and migrated successfully")
 }
 
+// dropOTPPhoneNumbers removes the plaintext phone_number column that OTP
+// challenges had before they were keyed by phone_hash, together with the
+// index built on it. OTPs pending at the time of the upgrade stop working.
+func dropOTPPhoneNumbers(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasColumn(&models.OTPChallenge{}, "phone_number") {
+		return nil
+	}
+	if migrator.HasIndex(&models.OTPChallenge{}, "idx_otp_phone_created") {
+		if err := migrator.DropIndex(&models.OTPChallenge{}, "idx_otp_phone_created"); err != nil {
+			return err
+		}
+	}
+	return migrator.DropColumn(&models.OTPChallenge{}, "phone_number")
+}
+
 // getEnv gets environment variable with fallback
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
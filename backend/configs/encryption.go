@@ -0,0 +1,41 @@
+package configs
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"meesho-clone/internal/pii"
+	"os"
+)
+
+// LoadEncryptionKeys configures the keys used to encrypt personal data at rest.
+// PII_ENCRYPTION_KEY and PII_BLIND_INDEX_KEY are base64-encoded 32 byte keys.
+func LoadEncryptionKeys() {
+	encryptionKey := loadKey("PII_ENCRYPTION_KEY")
+	blindIndexKey := loadKey("PII_BLIND_INDEX_KEY")
+
+	if err := pii.Configure(encryptionKey, blindIndexKey); err != nil {
+		log.Fatal("Failed to configure encryption:", err)
+	}
+}
+
+// loadKey decodes a key from the environment. Without one a fixed
+// development key is used so that local data stays readable across restarts;
+// outside development a missing key stops the server.
+func loadKey(name string) []byte {
+	value := os.Getenv(name)
+	if value == "" {
+		if !IsDevelopment() {
+			log.Fatalf("%s must be set unless APP_ENV=development", name)
+		}
+		log.Printf("Warning: %s not set. Using an insecure development key.", name)
+		key := sha256.Sum256([]byte("meesho-clone-development-" + name))
+		return key[:]
+	}
+
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		log.Fatalf("Failed to decode %s: %v", name, err)
+	}
+	return key
+}
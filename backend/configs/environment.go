@@ -0,0 +1,25 @@
+package configs
+
+import (
+	"os"
+	"strings"
+)
+
+// IsDevelopment reports whether APP_ENV marks this as a local development
+// instance, which enables stand-ins such as the fake payment gateway
+func IsDevelopment() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("APP_ENV")), "development")
+}
+
+// TrustedProxies returns the comma separated proxy addresses or CIDRs in
+// TRUSTED_PROXIES whose X-Forwarded-For header is believed. Without any, the
+// client IP is the address of the connection itself.
+func TrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
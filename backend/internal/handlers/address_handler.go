@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"meesho-clone/internal/models"
+	"meesho-clone/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddressHandler handles delivery address book requests
+type AddressHandler struct {
+	addressService *services.AddressService
+}
+
+// NewAddressHandler creates a new address handler
+func NewAddressHandler() *AddressHandler {
+	return &AddressHandler{
+		addressService: services.NewAddressService(),
+	}
+}
+
+// ListAddresses returns all addresses of a user
+func (h *AddressHandler) ListAddresses(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
+
+	addresses, err := h.addressService.ListAddresses(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch addresses",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    addresses,
+		"total":   len(addresses),
+	})
+}
+
+// CreateAddress adds a new address for a user
+func (h *AddressHandler) CreateAddress(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	address, err := h.addressService.CreateAddress(userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to create address",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    address,
+	})
+}
+
+// UpdateAddress replaces an existing address
+func (h *AddressHandler) UpdateAddress(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
+	addressID := c.Param("address_id")
+
+	var req models.AddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	address, err := h.addressService.UpdateAddress(userID, addressID, req)
+	if h.handleAddressError(c, err, "Failed to update address") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    address,
+	})
+}
+
+// SetDefaultAddress marks an address as the user's default
+func (h *AddressHandler) SetDefaultAddress(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
+
+	address, err := h.addressService.SetDefaultAddress(userID, c.Param("address_id"))
+	if h.handleAddressError(c, err, "Failed to set default address") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    address,
+	})
+}
+
+// DeleteAddress removes an address
+func (h *AddressHandler) DeleteAddress(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.addressService.DeleteAddress(userID, c.Param("address_id"))
+	if h.handleAddressError(c, err, "Failed to delete address") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Address deleted successfully",
+	})
+}
+
+// handleAddressError writes an error response and returns true if err is set
+func (h *AddressHandler) handleAddressError(c *gin.Context, err error, message string) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, services.ErrAddressNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Address not found",
+		})
+		return true
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"error":   message,
+		"details": err.Error(),
+	})
+	return true
+}
+
+// writeDeliveryAddressError writes the response for an address that cannot be used for an order
+func writeDeliveryAddressError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAddressNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Delivery address not found",
+		})
+	case errors.Is(err, services.ErrAddressNotServiceable):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error":   "Delivery address is not serviceable for this item",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to validate delivery address",
+			"details": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"meesho-clone/internal/middleware"
+	"meesho-clone/internal/models"
+	"meesho-clone/internal/services"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler handles operational endpoints
+type AdminHandler struct {
+	outboxService  *services.OutboxService
+	orderService   *services.OrderService
+	paymentService *services.PaymentService
+	userService    *services.UserService
+	rtoImporter    *services.RTOImportService
+	rtoAgeService  *services.RTOAgeService
+	addressService *services.AddressService
+	trending       *services.TrendingService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(outboxService *services.OutboxService) *AdminHandler {
+	return &AdminHandler{
+		outboxService:  outboxService,
+		orderService:   services.NewOrderService(),
+		paymentService: services.NewPaymentService(),
+		userService:    services.NewUserService(),
+		rtoImporter:    services.NewRTOImportService(),
+		rtoAgeService:  services.NewRTOAgeService(),
+		addressService: services.NewAddressService(),
+		trending:       services.NewTrendingService(),
+	}
+}
+
+// UpdateOrderStatusRequest represents the request for moving an order to a new status
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// ListOutboxEvents returns outbox events that are still pending or have failed
+func (h *AdminHandler) ListOutboxEvents(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.OutboxStatusPending, models.OutboxStatusFailed, models.OutboxStatusDelivered, models.OutboxStatusSkipped:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "status must be one of pending, failed, delivered or skipped",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "limit must be between 1 and 1000",
+		})
+		return
+	}
+
+	events, err := h.outboxService.ListEvents(status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch outbox events",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    events,
+		"total":   len(events),
+	})
+}
+
+// RetryOutboxEvent re-queues a failed outbox event
+func (h *AdminHandler) RetryOutboxEvent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid outbox event id",
+		})
+		return
+	}
+
+	if err := h.outboxService.RetryEvent(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Failed to retry outbox event",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Outbox event queued for retry",
+	})
+}
+
+// UpdateOrderStatus moves an order through the order state machine.
+// Cancelled and returned orders give their units back to the RTO list and
+// are refunded.
+func (h *AdminHandler) UpdateOrderStatus(c *gin.Context) {
+	orderID := c.Param("order_id")
+
+	var req UpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !models.IsValidOrderStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Unknown order status",
+		})
+		return
+	}
+
+	actor := services.StaffActor(middleware.CurrentUserID(c))
+	order, err := h.orderService.ChangeStatus(orderID, req.Status, actor, req.Reason)
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Order not found",
+		})
+		return
+	case errors.Is(err, services.ErrInvalidOrderTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Order status transition not allowed",
+			"details": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to update order status",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Cash is collected when the courier delivers the order
+	if order.Status == models.OrderStatusDelivered {
+		if err := h.paymentService.CollectCashOnDelivery(orderID); err != nil {
+			fmt.Printf("Warning: Failed to record COD collection for order %s: %v\n", orderID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Order status updated",
+		"data":    order,
+	})
+}
+
+// UpdateUserRole assigns a role to a user
+func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
+	userID := c.Param("user_id")
+
+	var req models.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Keeps an admin from locking themselves out; another admin has to do it
+	if userID == middleware.CurrentUserID(c) && req.Role != models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "You cannot remove your own admin role",
+		})
+		return
+	}
+
+	user, err := h.userService.SetRole(userID, req.Role)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "User not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"user_id":     user.UserID,
+			"role":        user.Role,
+			"permissions": models.RolePermissions(user.Role),
+		},
+	})
+}
+
+// maxManifestSize caps the size of an uploaded RTO manifest
+const maxManifestSize = 10 << 20
+
+// ImportRTOManifest loads a courier return manifest into the RTO store.
+// The manifest is sent as a multipart "file" field or as the raw request
+// body; its format comes from ?format=, the file extension or the content type.
+// With ?dry_run=true the rows are only validated.
+func (h *AdminHandler) ImportRTOManifest(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxManifestSize)
+
+	source := "request body"
+	format := c.Query("format")
+	var manifest io.Reader = c.Request.Body
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Manifest file is required",
+				"details": err.Error(),
+			})
+			return
+		}
+		opened, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Failed to read manifest file",
+				"details": err.Error(),
+			})
+			return
+		}
+		defer opened.Close()
+
+		source = file.Filename
+		manifest = opened
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+		}
+	}
+
+	if format == "" {
+		switch c.ContentType() {
+		case "text/csv":
+			format = services.RTOManifestCSV
+		case gin.MIMEJSON:
+			format = services.RTOManifestJSON
+		}
+	}
+
+	rows, err := services.ParseRTOManifest(manifest, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid manifest",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	report, err := h.rtoImporter.Import(source, format, rows, dryRun)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to import manifest",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	fmt.Printf("RTO manifest %s imported by %s: %d accepted, %d rejected (dry run: %t)\n",
+		source, middleware.CurrentUserID(c), report.AcceptedCount, report.RejectedCount, dryRun)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
+
+// ListRTOAgePolicies returns the configured RTO age policies
+func (h *AdminHandler) ListRTOAgePolicies(c *gin.Context) {
+	policies, err := h.rtoAgeService.ListPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to list RTO age policies",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policies,
+		"total":   len(policies),
+	})
+}
+
+// UpdateRTOAgePolicy sets how long RTO units of a category stay listed and
+// how they are marked down. The category "*" sets the default policy.
+func (h *AdminHandler) UpdateRTOAgePolicy(c *gin.Context) {
+	var req models.UpdateRTOAgePolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	actor := services.StaffActor(middleware.CurrentUserID(c))
+	policy, err := h.rtoAgeService.SetPolicy(c.Param("category"), req, actor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Failed to update RTO age policy",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// ListServiceableRules returns the pincodes each RTO code delivers to,
+// optionally for one ?code=
+func (h *AdminHandler) ListServiceableRules(c *gin.Context) {
+	rules, err := h.addressService.ListServiceableRules(c.Query("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to list serviceability rules",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rules,
+		"total":   len(rules),
+	})
+}
+
+// SetServiceablePincodes replaces the pincodes and pincode prefixes an RTO
+// code delivers to. Orders and checkouts are only accepted for addresses
+// matching a rule of the code holding the unit.
+func (h *AdminHandler) SetServiceablePincodes(c *gin.Context) {
+	var req models.ServiceablePincodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rules, err := h.addressService.SetServiceablePincodes(c.Param("code"), req.Pincodes)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidServiceableRule) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"error":   "Failed to update serviceability rules",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rules,
+		"total":   len(rules),
+	})
+}
+
+// ListTrendingCatalogs returns the computed trending catalogs with the pins
+// and blocklist applied: for ?city= or ?category= when given, else global
+func (h *AdminHandler) ListTrendingCatalogs(c *gin.Context) {
+	scope, value := models.TrendingScopeGlobal, ""
+	if city := c.Query("city"); city != "" {
+		scope, value = models.TrendingScopeCity, city
+	} else if category := c.Query("category"); category != "" {
+		scope, value = models.TrendingScopeCategory, category
+	}
+
+	catalogIDs, err := h.trending.TrendingCatalogIDs(scope, value)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to list trending catalogs",
+			"details": err.Error(),
+		})
+		return
+	}
+	if catalogIDs == nil {
+		catalogIDs = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"scope":       scope,
+			"scope_value": value,
+			"catalog_ids": catalogIDs,
+		},
+		"total": len(catalogIDs),
+	})
+}
+
+// ListCatalogOverrides returns the pinned and blocklisted catalogs
+func (h *AdminHandler) ListCatalogOverrides(c *gin.Context) {
+	overrides, err := h.trending.ListOverrides()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to list catalog overrides",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    overrides,
+		"total":   len(overrides),
+	})
+}
+
+// SetCatalogOverride pins a catalog to the top of the trending catalogs or blocklists it
+func (h *AdminHandler) SetCatalogOverride(c *gin.Context) {
+	var req models.CatalogOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	catalogID := c.Param("catalog_id")
+	if len(catalogID) < 4 || len(catalogID) > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid catalog_id",
+		})
+		return
+	}
+
+	actor := services.StaffActor(middleware.CurrentUserID(c))
+	override, err := h.trending.SetOverride(catalogID, req, actor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to update catalog override",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    override,
+	})
+}
+
+// RemoveCatalogOverride unpins or un-blocklists a catalog
+func (h *AdminHandler) RemoveCatalogOverride(c *gin.Context) {
+	if err := h.trending.RemoveOverride(c.Param("catalog_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Catalog override not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Catalog override removed",
+	})
+}
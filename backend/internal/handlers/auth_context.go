@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"meesho-clone/internal/middleware"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authorizeUserParam returns the :user_id path parameter if it belongs to the
+// authenticated user, and writes a 403 response otherwise
+func authorizeUserParam(c *gin.Context) (string, bool) {
+	userID := c.Param("user_id")
+	if userID == "" || userID != middleware.CurrentUserID(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "You can only access your own account",
+		})
+		return "", false
+	}
+	return userID, true
+}
@@ -1,33 +1,57 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"meesho-clone/internal/middleware"
 	"meesho-clone/internal/models"
+	"meesho-clone/internal/pii"
 	"meesho-clone/internal/services"
 	"net/http"
 	"regexp"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // AuthHandler handles authentication related requests
 type AuthHandler struct {
-	userService *services.UserService
+	userService    *services.UserService
+	otpService     *services.OTPService
+	tokenService   *services.TokenService
+	sessionService *services.SessionService
+	accountService *services.AccountService
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler() *AuthHandler {
+func NewAuthHandler(tokenService *services.TokenService, otpService *services.OTPService) *AuthHandler {
 	return &AuthHandler{
-		userService: services.NewUserService(),
+		userService:    services.NewUserService(),
+		otpService:     otpService,
+		tokenService:   tokenService,
+		sessionService: services.NewSessionService(),
+		accountService: services.NewAccountService(),
 	}
 }
 
-// Login handles user login with phone number
+// Login used to log in with just a phone number. Logging in now requires
+// proving ownership of the number through the OTP flow.
 func (h *AuthHandler) Login(c *gin.Context) {
-	var req models.LoginRequest
+	c.JSON(http.StatusGone, gin.H{
+		"success": false,
+		"error":   "Phone-only login is no longer supported",
+		"details": "Request an OTP with POST /api/v1/auth/otp/request and log in with POST /api/v1/auth/otp/verify",
+	})
+}
 
-	// Bind JSON request
+// RequestOTP sends a login OTP to a phone number
+func (h *AuthHandler) RequestOTP(c *gin.Context) {
+	var req models.OTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
 			"error":   "Invalid request format",
 			"details": err.Error(),
 		})
@@ -37,27 +61,70 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Validate phone number format (Indian mobile numbers)
 	if !h.isValidIndianPhoneNumber(req.PhoneNumber) {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid phone number format. Please enter a valid 10-digit Indian mobile number",
+			"success": false,
+			"error":   "Invalid phone number format. Please enter a valid 10-digit Indian mobile number",
 		})
 		return
 	}
 
+	result, err := h.otpService.RequestOTP(models.OTPPurposeLogin, req.PhoneNumber, c.ClientIP())
+	if err != nil {
+		respondOTPRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "OTP sent",
+		"data":    result,
+	})
+}
+
+// VerifyOTP checks a login OTP and logs the user in, creating the account on first login
+func (h *AuthHandler) VerifyOTP(c *gin.Context) {
+	var req models.OTPVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.otpService.VerifyOTP(models.OTPPurposeLogin, req.PhoneNumber, req.Code); err != nil {
+		respondOTPVerifyError(c, err)
+		return
+	}
+
 	// Create or get user
 	user, err := h.userService.CreateOrGetUser(req.PhoneNumber)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
 			"error":   "Failed to process login",
 			"details": err.Error(),
 		})
 		return
 	}
 
+	tokens, err := h.tokenService.IssueTokens(user.UserID, sessionInfo(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to issue tokens",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	// Prepare response
 	response := models.LoginResponse{
 		UserID:      user.UserID,
-		PhoneNumber: user.PhoneNumber,
+		PhoneNumber: pii.MaskPhone(user.PhoneNumber),
 		Name:        user.Name,
 		Message:     "Login successful",
+		Tokens:      tokens,
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -66,17 +133,150 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
-// GetUserProfile retrieves user profile information
-func (h *AuthHandler) GetUserProfile(c *gin.Context) {
-	userID := c.Param("user_id")
+// RefreshToken exchanges a refresh token for a new token pair
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	if userID == "" {
+	tokens, err := h.tokenService.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenRevoked) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "Invalid refresh token. Please log in again",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to refresh tokens",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+// Logout revokes the refresh token and every token rotated from the same login
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
 		})
 		return
 	}
 
+	if err := h.tokenService.Revoke(req.RefreshToken); err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "Invalid refresh token",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to log out",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out",
+	})
+}
+
+// ListSessions returns the active sessions of the authenticated user
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.sessionService.ListSessions(middleware.CurrentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch sessions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	currentSessionID := middleware.CurrentSessionID(c)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].SessionID == currentSessionID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    sessions,
+		"total":   len(sessions),
+	})
+}
+
+// RevokeSession logs out one session of the authenticated user
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	err := h.sessionService.RevokeSession(middleware.CurrentUserID(c), c.Param("session_id"))
+	if errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Session not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to log out session",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session logged out",
+	})
+}
+
+// RevokeAllSessions logs the authenticated user out of every device
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	if err := h.sessionService.RevokeAllSessions(middleware.CurrentUserID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to log out sessions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out of all sessions",
+	})
+}
+
+// GetUserProfile retrieves user profile information
+func (h *AuthHandler) GetUserProfile(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
+
 	user, err := h.userService.GetUserByID(userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -94,44 +294,183 @@ func (h *AuthHandler) GetUserProfile(c *gin.Context) {
 
 // UpdateUserProfile updates user profile information
 func (h *AuthHandler) UpdateUserProfile(c *gin.Context) {
-	userID := c.Param("user_id")
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
 
-	if userID == "" {
+	// Decoded by hand so that unknown fields such as deleted_at are rejected
+	// instead of silently ignored
+	var req models.UpdateProfileRequest
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "User ID is required",
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid profile details",
+			"details": err.Error(),
 		})
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
+	user, err := h.userService.UpdateProfile(userID, &req, services.UserActor(middleware.CurrentUserID(c)))
+	if errors.Is(err, services.ErrNoProfileChanges) {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request format",
+			"success": false,
+			"error":   "No profile fields to update",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to update user profile",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	// Remove fields that shouldn't be updated directly
-	delete(updates, "id")
-	delete(updates, "user_id")
-	delete(updates, "phone_number")
-	delete(updates, "created_at")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Profile updated successfully",
+		"data":    user,
+	})
+}
+
+// DeleteAccount deletes the authenticated user's account and logs out every session
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
 
-	err := h.userService.UpdateUser(userID, updates)
+	if err := h.accountService.DeleteAccount(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to delete account",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Account deleted. Log in again before your data is erased to restore it",
+	})
+}
+
+// ExportUserData downloads the authenticated user's personal data as a zip of JSON files
+func (h *AuthHandler) ExportUserData(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
+
+	archive, err := h.accountService.ExportUserData(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to update user profile",
+			"success": false,
+			"error":   "Failed to export user data",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	// Get updated user data
-	user, err := h.userService.GetUserByID(userID)
+	filename := fmt.Sprintf("meesho-data-%s.zip", userID)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// RequestPhoneChange sends OTPs to the current and the new phone number of the authenticated user
+func (h *AuthHandler) RequestPhoneChange(c *gin.Context) {
+	var req models.PhoneChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !h.isValidIndianPhoneNumber(req.NewPhoneNumber) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid phone number format. Please enter a valid 10-digit Indian mobile number",
+		})
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	if err := h.userService.CheckPhoneNumberAvailable(user.UserID, req.NewPhoneNumber); err != nil {
+		respondPhoneChangeError(c, err)
+		return
+	}
+
+	oldResult, err := h.otpService.RequestOTP(models.OTPPurposePhoneChangeOld, user.PhoneNumber, c.ClientIP())
+	if err != nil {
+		respondOTPRequestError(c, err)
+		return
+	}
+	newResult, err := h.otpService.RequestOTP(models.OTPPurposePhoneChangeNew, req.NewPhoneNumber, c.ClientIP())
+	if err != nil {
+		respondOTPRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "OTPs sent to your current and new phone numbers",
+		"data": gin.H{
+			"old_number": oldResult,
+			"new_number": newResult,
+		},
+	})
+}
+
+// ConfirmPhoneChange verifies both OTPs and moves the authenticated user to the
+// new phone number. Every existing session is logged out and a new one is
+// started for the calling device.
+func (h *AuthHandler) ConfirmPhoneChange(c *gin.Context) {
+	var req models.PhoneChangeVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user := middleware.CurrentUser(c)
+	err := h.otpService.VerifyOTPs(
+		services.OTPCheck{Purpose: models.OTPPurposePhoneChangeOld, PhoneNumber: user.PhoneNumber, Code: req.OldCode},
+		services.OTPCheck{Purpose: models.OTPPurposePhoneChangeNew, PhoneNumber: req.NewPhoneNumber, Code: req.NewCode},
+	)
+	if err != nil {
+		respondOTPVerifyError(c, err)
+		return
+	}
+
+	updated, err := h.userService.ChangePhoneNumber(user.UserID, req.NewPhoneNumber)
+	if err != nil {
+		respondPhoneChangeError(c, err)
+		return
+	}
+
+	tokens, err := h.tokenService.IssueTokens(updated.UserID, sessionInfo(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to fetch updated user data",
+			"success": false,
+			"error":   "Phone number updated but failed to issue tokens. Please log in again",
 			"details": err.Error(),
 		})
 		return
@@ -139,51 +478,165 @@ func (h *AuthHandler) UpdateUserProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "Profile updated successfully",
-		"data":    user,
+		"data": models.LoginResponse{
+			UserID:      updated.UserID,
+			PhoneNumber: pii.MaskPhone(updated.PhoneNumber),
+			Name:        updated.Name,
+			Message:     "Phone number updated",
+			Tokens:      tokens,
+		},
 	})
 }
 
-// ValidateUser validates if a user exists
+// ValidateUser tells a client how to proceed with a phone number or account.
+// Anonymous callers only learn whether OTP login is possible for a well formed
+// number; the answer is the same whether or not it is registered, since
+// verifying an OTP signs new numbers up. An authenticated caller gets their
+// own account details. Every call is audit logged.
 func (h *AuthHandler) ValidateUser(c *gin.Context) {
 	userID := c.Query("user_id")
 	phoneNumber := c.Query("phone_number")
 
-	if userID == "" && phoneNumber == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Either user_id or phone_number is required",
+	entry := models.UserLookupAudit{
+		QueryUserID: userID,
+		IPAddress:   c.ClientIP(),
+	}
+	if phoneNumber != "" {
+		entry.PhoneHash = *models.PhoneHashOf(phoneNumber)
+	}
+	defer func() {
+		h.userService.RecordLookup(entry)
+	}()
+
+	if user := middleware.CurrentUser(c); user != nil {
+		entry.CallerUserID = user.UserID
+		entry.Authenticated = true
+
+		if (userID != "" && userID != user.UserID) || (phoneNumber != "" && phoneNumber != user.PhoneNumber) {
+			entry.Outcome = "forbidden"
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "You can only access your own account",
+			})
+			return
+		}
+
+		entry.Outcome = "self"
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"exists":  true,
+			"data":    user,
 		})
 		return
 	}
 
-	var user *models.User
-	var err error
-
-	if userID != "" {
-		user, err = h.userService.GetUserByID(userID)
-	} else {
-		user, err = h.userService.GetUserByPhoneNumber(phoneNumber)
+	if phoneNumber == "" {
+		entry.Outcome = "missing_phone"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "phone_number is required",
+		})
+		return
 	}
 
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
+	if !h.isValidIndianPhoneNumber(phoneNumber) {
+		entry.Outcome = "invalid_phone"
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "User not found",
+			"error":   "Invalid phone number format. Please enter a valid 10-digit Indian mobile number",
 		})
 		return
 	}
 
+	entry.Outcome = "otp_available"
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"exists":  true,
 		"data": gin.H{
-			"user_id":      user.UserID,
-			"phone_number": user.PhoneNumber,
-			"name":         user.Name,
+			"phone_number":        pii.MaskPhone(phoneNumber),
+			"otp_login_available": true,
+			"next_step":           "/api/v1/auth/otp/request",
 		},
 	})
 }
 
+// sessionInfo describes the device making the request for a new session.
+// Clients may name the device; otherwise the user agent describes it.
+func sessionInfo(c *gin.Context) models.SessionInfo {
+	deviceInfo := c.GetHeader("X-Device-Name")
+	if deviceInfo == "" {
+		deviceInfo = c.Request.UserAgent()
+	}
+	return models.SessionInfo{
+		DeviceInfo: deviceInfo,
+		IPAddress:  c.ClientIP(),
+	}
+}
+
+// respondOTPRequestError writes the response for a failed OTP send
+func respondOTPRequestError(c *gin.Context, err error) {
+	var throttleErr *services.OTPThrottleError
+	if errors.As(err, &throttleErr) {
+		c.Header("Retry-After", fmt.Sprintf("%d", int(math.Ceil(throttleErr.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"error":   "Too many OTP requests. Please try again later",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"error":   "Failed to send OTP",
+		"details": err.Error(),
+	})
+}
+
+// respondOTPVerifyError writes the response for a failed OTP verification
+func respondOTPVerifyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrOTPInvalid), errors.Is(err, services.ErrOTPExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error":   "Invalid or expired OTP",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrOTPAttemptsExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"error":   "Too many incorrect attempts. Please request a new OTP",
+			"details": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to verify OTP",
+			"details": err.Error(),
+		})
+	}
+}
+
+// respondPhoneChangeError writes the response for a rejected phone number change
+func respondPhoneChangeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPhoneNumberInUse):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "This phone number is already registered to another account",
+		})
+	case errors.Is(err, services.ErrSamePhoneNumber):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "New phone number is the same as the current one",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to change phone number",
+			"details": err.Error(),
+		})
+	}
+}
+
 // isValidIndianPhoneNumber validates Indian mobile number format
 func (h *AuthHandler) isValidIndianPhoneNumber(phone string) bool {
 	// Indian mobile numbers: 10 digits starting with 6, 7, 8, or 9
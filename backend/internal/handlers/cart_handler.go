@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"meesho-clone/internal/middleware"
+	"meesho-clone/internal/models"
+	"meesho-clone/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CartHandler handles cart and checkout requests
+type CartHandler struct {
+	cartService      *services.CartService
+	userService      *services.UserService
+	addressService   *services.AddressService
+	paymentService   *services.PaymentService
+	orderIDGenerator *services.OrderIDGenerator
+}
+
+// NewCartHandler creates a new cart handler
+func NewCartHandler() *CartHandler {
+	return &CartHandler{
+		cartService:      services.NewCartService(),
+		userService:      services.NewUserService(),
+		addressService:   services.NewAddressService(),
+		paymentService:   services.NewPaymentService(),
+		orderIDGenerator: services.DefaultOrderIDGenerator(),
+	}
+}
+
+// AddCartItemRequest represents the request for adding a product to the cart
+type AddCartItemRequest struct {
+	ProductID string `json:"product_id" binding:"required"`
+	CatalogID string `json:"catalog_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,min=1"`
+
+	// RTOCode is the code holding the unit, as listed in the catalog
+	RTOCode string `json:"rto_code" binding:"max=20"`
+}
+
+// UpdateCartItemRequest represents the request for changing a cart item quantity
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1"`
+}
+
+// CheckoutRequest represents the request for checking out the cart
+type CheckoutRequest struct {
+	AddressID     string `json:"address_id" binding:"required"`
+	PaymentMethod string `json:"payment_method" binding:"omitempty,oneof=cod online"`
+}
+
+// GetCart lists the items in the user's cart
+func (h *CartHandler) GetCart(c *gin.Context) {
+	userID := middleware.CurrentUserID(c)
+
+	cart, err := h.cartService.GetCart(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch cart",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cart,
+	})
+}
+
+// AddItem adds a product to the user's cart
+func (h *CartHandler) AddItem(c *gin.Context) {
+	var req AddCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID := middleware.CurrentUserID(c)
+
+	rtoCode := req.RTOCode
+	if rtoCode == "" {
+		userCode, err := h.userService.GetUserCode(userID)
+		if err != nil {
+			fmt.Printf("Warning: Failed to get user code: %v\n", err)
+		}
+		rtoCode = userCode
+	}
+
+	item, err := h.cartService.AddItem(userID, req.ProductID, req.CatalogID, rtoCode, req.Quantity)
+	if writeUnitClaimError(c, err, nil) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to add item to cart",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    item,
+	})
+}
+
+// UpdateItem changes the quantity of a product in the user's cart
+func (h *CartHandler) UpdateItem(c *gin.Context) {
+	productID := c.Param("product_id")
+
+	var req UpdateCartItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	item, err := h.cartService.UpdateItem(middleware.CurrentUserID(c), productID, req.Quantity)
+	if errors.Is(err, services.ErrCartItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Item not in cart",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to update cart item",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    item,
+	})
+}
+
+// RemoveItem removes a product from the user's cart
+func (h *CartHandler) RemoveItem(c *gin.Context) {
+	productID := c.Param("product_id")
+	err := h.cartService.RemoveItem(middleware.CurrentUserID(c), productID)
+	if errors.Is(err, services.ErrCartItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Item not in cart",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to remove cart item",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Item removed from cart",
+	})
+}
+
+// Checkout turns the user's cart into a single order
+func (h *CartHandler) Checkout(c *gin.Context) {
+	var req CheckoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID := middleware.CurrentUserID(c)
+
+	// Get user code from user_mapping table (empty if the user has no mapping)
+	userCode, err := h.userService.GetUserCode(userID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to get user code: %v\n", err)
+	}
+
+	// Items ship from different codes, so serviceability is checked per item
+	address, err := h.addressService.GetAddress(userID, req.AddressID)
+	if err != nil {
+		writeDeliveryAddressError(c, err)
+		return
+	}
+
+	orderID := h.orderIDGenerator.NextID()
+	result, err := h.cartService.Checkout(userID, userCode, orderID, address)
+	switch {
+	case errors.Is(err, services.ErrCartEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Cart has no items that can be ordered",
+			"data":    result,
+		})
+		return
+	case writeUnitClaimError(c, err, result):
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to checkout cart",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = models.PaymentMethodCOD
+	}
+
+	// Start collecting payment; this confirms COD orders right away
+	payment, order, err := h.paymentService.StartPayment(result.Order, req.PaymentMethod)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success":  false,
+			"error":    "Failed to start payment, order cancelled",
+			"details":  err.Error(),
+			"order_id": orderID,
+		})
+		return
+	}
+	order.Items = result.Order.Items
+	result.Order = order
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Order placed successfully",
+		"data":    result,
+		"payment": payment,
+	})
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"meesho-clone/internal/middleware"
+	"meesho-clone/internal/models"
 	"meesho-clone/internal/services"
 	"net/http"
 
@@ -10,52 +12,65 @@ import (
 // CatalogHandler handles catalog-related requests
 type CatalogHandler struct {
 	catalogService *services.CatalogService
-	userService    *services.UserService
 }
 
 // NewCatalogHandler creates a new catalog handler
 func NewCatalogHandler() *CatalogHandler {
 	return &CatalogHandler{
 		catalogService: services.NewCatalogService(),
-		userService:    services.NewUserService(),
 	}
 }
 
 // GetCatalogData handles the banner widget click and returns catalog data
 func (h *CatalogHandler) GetCatalogData(c *gin.Context) {
-	// Get user_id from query parameter (simplified approach)
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
+	userID := middleware.CurrentUserID(c)
+
+	// Fetch catalog data using hardcoded catalog IDs
+	catalogResponse, err := h.catalogService.GetCatalogData(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   "user_id is required as query parameter",
+			"error":   "Failed to fetch catalog data",
+			"details": err.Error(),
 		})
 		return
 	}
 
-	// Validate that user exists
-	_, err := h.userService.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
+	c.JSON(http.StatusOK, catalogResponse)
+}
+
+// RecordCatalogClick records that the user opened a catalog, feeding the trending catalogs
+func (h *CatalogHandler) RecordCatalogClick(c *gin.Context) {
+	var req models.CatalogClickRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"error":   "User not found",
+			"error":   "Invalid request format",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	// Fetch catalog data using hardcoded catalog IDs
-	catalogResponse, err := h.catalogService.GetCatalogData(userID)
-	if err != nil {
+	if len(h.catalogService.ValidateCatalogIDs([]string{req.CatalogID})) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid catalog_id",
+		})
+		return
+	}
+
+	if err := h.catalogService.RecordCatalogClick(middleware.CurrentUserID(c), req.CatalogID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
-			"error":   "Failed to fetch catalog data",
+			"error":   "Failed to record catalog click",
 			"details": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, catalogResponse)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+	})
 }
 
 // HealthCheck provides health check for catalog service
@@ -1,7 +1,8 @@
 package handlers
 
 import (
-	"meesho-clone/internal/models"
+	"meesho-clone/internal/middleware"
+	"meesho-clone/internal/pii"
 	"meesho-clone/internal/services"
 	"net/http"
 
@@ -10,47 +11,22 @@ import (
 
 // HomescreenHandler handles homescreen related requests
 type HomescreenHandler struct {
-	userService   *services.UserService
 	meeshoService *services.MeeshoService
 }
 
 // NewHomescreenHandler creates a new homescreen handler
 func NewHomescreenHandler() *HomescreenHandler {
 	return &HomescreenHandler{
-		userService:   services.NewUserService(),
 		meeshoService: services.NewMeeshoService(),
 	}
 }
 
 // GetHomescreen fetches homescreen data for a user
 func (h *HomescreenHandler) GetHomescreen(c *gin.Context) {
-	var req models.HomescreenRequest
-
-	// Try to get user_id from JSON body first, then from query params
-	if err := c.ShouldBindJSON(&req); err != nil {
-		// If JSON binding fails, try query params
-		userID := c.Query("user_id")
-		if userID == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "user_id is required in request body or query parameter",
-			})
-			return
-		}
-		req.UserID = userID
-	}
-
-	// Validate that user exists
-	user, err := h.userService.GetUserByID(req.UserID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":   "User not found",
-			"details": err.Error(),
-		})
-		return
-	}
+	user := middleware.CurrentUser(c)
 
 	// Fetch homescreen data from Meesho API
-	meeshoData, err := h.meeshoService.FetchHomescreenData(req.UserID)
+	meeshoData, err := h.meeshoService.FetchHomescreenData(user.UserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to fetch homescreen data",
@@ -60,13 +36,13 @@ func (h *HomescreenHandler) GetHomescreen(c *gin.Context) {
 	}
 
 	// Format response for frontend consumption
-	response := h.meeshoService.FormatHomescreenResponse(meeshoData, req.UserID)
+	response := h.meeshoService.FormatHomescreenResponse(meeshoData, user.UserID)
 
 	// Add user information to response
 	response["user_info"] = gin.H{
 		"user_id": user.UserID,
 		"name":    user.Name,
-		"phone":   user.PhoneNumber,
+		"phone":   pii.MaskPhone(user.PhoneNumber),
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -74,22 +50,7 @@ func (h *HomescreenHandler) GetHomescreen(c *gin.Context) {
 
 // GetCategories fetches just the categories from navigation
 func (h *HomescreenHandler) GetCategories(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "user_id is required",
-		})
-		return
-	}
-
-	// Validate user exists
-	_, err := h.userService.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
-		return
-	}
+	userID := middleware.CurrentUserID(c)
 
 	// Fetch data from Meesho API
 	meeshoData, err := h.meeshoService.FetchHomescreenData(userID)
@@ -123,22 +84,7 @@ func (h *HomescreenHandler) GetCategories(c *gin.Context) {
 
 // GetProducts fetches just the products/widgets
 func (h *HomescreenHandler) GetProducts(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "user_id is required",
-		})
-		return
-	}
-
-	// Validate user exists
-	_, err := h.userService.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
-		return
-	}
+	userID := middleware.CurrentUserID(c)
 
 	// Fetch data from Meesho API
 	meeshoData, err := h.meeshoService.FetchHomescreenData(userID)
@@ -178,16 +124,9 @@ func (h *HomescreenHandler) GetProducts(c *gin.Context) {
 
 // SearchProducts handles product search
 func (h *HomescreenHandler) SearchProducts(c *gin.Context) {
-	userID := c.Query("user_id")
+	userID := middleware.CurrentUserID(c)
 	query := c.Query("q")
 
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "user_id is required",
-		})
-		return
-	}
-
 	if query == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "search query 'q' is required",
@@ -195,15 +134,6 @@ func (h *HomescreenHandler) SearchProducts(c *gin.Context) {
 		return
 	}
 
-	// Validate user exists
-	_, err := h.userService.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
-		return
-	}
-
 	// Use Meesho service to search (placeholder implementation)
 	results, err := h.meeshoService.SearchProducts(query, userID)
 	if err != nil {
@@ -224,16 +154,9 @@ func (h *HomescreenHandler) SearchProducts(c *gin.Context) {
 
 // GetProductDetails handles product detail requests
 func (h *HomescreenHandler) GetProductDetails(c *gin.Context) {
-	userID := c.Query("user_id")
+	userID := middleware.CurrentUserID(c)
 	productID := c.Param("product_id")
 
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "user_id is required",
-		})
-		return
-	}
-
 	if productID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "product_id is required",
@@ -241,15 +164,6 @@ func (h *HomescreenHandler) GetProductDetails(c *gin.Context) {
 		return
 	}
 
-	// Validate user exists
-	_, err := h.userService.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
-		return
-	}
-
 	// Get product details from Meesho service
 	details, err := h.meeshoService.GetProductDetails(productID, userID)
 	if err != nil {
@@ -270,22 +184,8 @@ func (h *HomescreenHandler) GetProductDetails(c *gin.Context) {
 
 // RefreshHomescreen forces a fresh fetch from Meesho API
 func (h *HomescreenHandler) RefreshHomescreen(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "user_id is required",
-		})
-		return
-	}
-
-	// Validate user exists
-	user, err := h.userService.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "User not found",
-		})
-		return
-	}
+	user := middleware.CurrentUser(c)
+	userID := user.UserID
 
 	// Force fresh fetch from Meesho API
 	meeshoData, err := h.meeshoService.FetchHomescreenData(userID)
@@ -303,7 +203,7 @@ func (h *HomescreenHandler) RefreshHomescreen(c *gin.Context) {
 	response["user_info"] = gin.H{
 		"user_id": user.UserID,
 		"name":    user.Name,
-		"phone":   user.PhoneNumber,
+		"phone":   pii.MaskPhone(user.PhoneNumber),
 	}
 
 	c.JSON(http.StatusOK, response)
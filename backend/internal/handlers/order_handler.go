@@ -2,70 +2,95 @@ package handlers
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"meesho-clone/configs"
+	"meesho-clone/internal/middleware"
 	"meesho-clone/internal/models"
 	"meesho-clone/internal/services"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // OrderHandler handles order-related requests
 type OrderHandler struct {
-	userService *services.UserService
+	orderService       *services.OrderService
+	outboxService      *services.OutboxService
+	idempotencyService *services.IdempotencyService
+	reservationService *services.ReservationService
+	addressService     *services.AddressService
+	paymentService     *services.PaymentService
+	invoiceService     *services.InvoiceService
+	orderIDGenerator   *services.OrderIDGenerator
 }
 
 // NewOrderHandler creates a new order handler
 func NewOrderHandler() *OrderHandler {
 	return &OrderHandler{
-		userService: services.NewUserService(),
+		orderService:       services.NewOrderService(),
+		outboxService:      services.NewOutboxService(),
+		idempotencyService: services.NewIdempotencyService(),
+		reservationService: services.NewReservationService(),
+		addressService:     services.NewAddressService(),
+		paymentService:     services.NewPaymentService(),
+		invoiceService:     services.NewInvoiceService(),
+		orderIDGenerator:   services.DefaultOrderIDGenerator(),
 	}
 }
 
 // PlaceOrderRequest represents the request for placing an order
 type PlaceOrderRequest struct {
-	UserID    string `json:"user_id" binding:"required"`
 	ProductID string `json:"product_id" binding:"required"`
 	CatalogID string `json:"catalog_id" binding:"required"`
 	Quantity  int    `json:"quantity" binding:"required,min=1"`
+	AddressID string `json:"address_id" binding:"required"`
+
+	// RTOCode is the code holding the unit, as listed in the catalog.
+	// Defaults to the user's own code.
+	RTOCode string `json:"rto_code" binding:"max=20"`
+
+	// PaymentMethod is "cod" (default) or "online"
+	PaymentMethod string `json:"payment_method" binding:"omitempty,oneof=cod online"`
 }
 
 // PlaceOrderResponse represents the response from place order API
 type PlaceOrderResponse struct {
-	Success   bool   `json:"success"`
-	Message   string `json:"message"`
-	OrderID   string `json:"order_id"`
-	ProductID string `json:"product_id"`
-	Quantity  int    `json:"quantity"`
+	Success   bool            `json:"success"`
+	Message   string          `json:"message"`
+	OrderID   string          `json:"order_id"`
+	ProductID string          `json:"product_id"`
+	Quantity  int             `json:"quantity"`
+	Status    string          `json:"status"`
+	Payment   *models.Payment `json:"payment,omitempty"`
 }
 
-// RTODeleteRequest represents the request to external RTO delete API
-type RTODeleteRequest struct {
-	Code      string `json:"code"`
-	ProductID int    `json:"product_id"`
-	CatalogID int    `json:"catalog_id"`
+// ReserveItemRequest represents the request for holding an RTO unit at checkout start
+type ReserveItemRequest struct {
+	ProductID string `json:"product_id" binding:"required"`
+	CatalogID string `json:"catalog_id" binding:"required"`
+	RTOCode   string `json:"rto_code" binding:"max=20"`
+}
+
+// CancelOrderRequest represents the request for cancelling an order
+type CancelOrderRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
 }
 
 // PlaceOrder handles the place order request
 func (h *OrderHandler) PlaceOrder(c *gin.Context) {
-	fmt.Printf("=== PLACE ORDER DEBUG START ===\n")
-
 	var req PlaceOrderRequest
 
-	// Log the raw request body
+	// Keep the raw body for the Idempotency-Key fingerprint
 	body, _ := io.ReadAll(c.Request.Body)
-	fmt.Printf("Raw request body: %s\n", string(body))
 
 	// Reset the body for binding
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
 	// Bind JSON request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"error":   "Invalid request format",
@@ -74,30 +99,94 @@ func (h *OrderHandler) PlaceOrder(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("Parsed request: %+v\n", req)
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = models.PaymentMethodCOD
+	}
+
+	userID := middleware.CurrentUserID(c)
+
+	// Replay or claim the Idempotency-Key, if the client sent one
+	var lease *services.IdempotencyLease
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		var recorder *responseRecorder
+		var ok bool
+		lease, recorder, ok = h.claimIdempotencyKey(c, userID, key, body)
+		if !ok {
+			return
+		}
+		defer h.completeIdempotencyKey(key, lease, recorder)
+	}
+
+	// Generate order ID
+	orderID := h.generateOrderID()
+
+	// Get user code from user_mapping table (empty if the user has no mapping)
+	userCode, err := h.getUserCode(userID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to get user code: %v\n", err)
+	}
+
+	// The unit ships from the code listing it, which may be a neighbour of the user's
+	rtoCode := req.RTOCode
+	if rtoCode == "" {
+		rtoCode = userCode
+	}
 
-	// Validate that user exists
-	fmt.Printf("Validating user: %s\n", req.UserID)
-	_, err := h.userService.GetUserByID(req.UserID)
+	// Make sure the item can be delivered to the chosen address
+	address, err := h.addressService.ResolveDeliveryAddress(userID, req.AddressID, rtoCode)
 	if err != nil {
-		fmt.Printf("User validation failed: %v\n", err)
-		c.JSON(http.StatusNotFound, gin.H{
+		writeDeliveryAddressError(c, err)
+		return
+	}
+
+	// Persist the order together with its RTO drop intent
+	order, err := h.orderService.CreateOrder(services.CreateOrderInput{
+		OrderID:  orderID,
+		UserID:   userID,
+		UserCode: userCode,
+		Address:  address,
+		Lease:    lease,
+		Items: []models.OrderItem{
+			{
+				ProductID: req.ProductID,
+				CatalogID: req.CatalogID,
+				Quantity:  req.Quantity,
+				RTOCode:   rtoCode,
+			},
+		},
+	})
+	if writeUnitClaimError(c, err, nil) {
+		return
+	}
+	if errors.Is(err, services.ErrIdempotencyLeaseLost) {
+		c.JSON(http.StatusConflict, gin.H{
 			"success": false,
-			"error":   "User not found",
+			"error":   "Order with this Idempotency-Key is being processed by a retry",
+		})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Failed to persist order %s: %v\n", orderID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to place order",
 			"details": err.Error(),
 		})
 		return
 	}
-	fmt.Printf("User validation successful\n")
 
-	// Generate order ID
-	orderID := h.generateOrderID()
-	fmt.Printf("Generated order ID: %s\n", orderID)
-
-	// Call external RTO delete API
-	fmt.Printf("Calling RTO drop API...\n")
-	rtoSuccess := h.callRTODropAPI(req.UserID, req.ProductID, req.CatalogID)
-	fmt.Printf("RTO drop API result: %v\n", rtoSuccess)
+	// Start collecting payment; this confirms COD orders right away
+	payment, order, err := h.paymentService.StartPayment(order, req.PaymentMethod)
+	if err != nil {
+		fmt.Printf("Failed to start payment for order %s: %v\n", orderID, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success":  false,
+			"error":    "Failed to start payment, order cancelled",
+			"details":  err.Error(),
+			"order_id": orderID,
+		})
+		return
+	}
 
 	// Create response
 	response := PlaceOrderResponse{
@@ -106,152 +195,390 @@ func (h *OrderHandler) PlaceOrder(c *gin.Context) {
 		OrderID:   orderID,
 		ProductID: req.ProductID,
 		Quantity:  req.Quantity,
+		Status:    order.Status,
+		Payment:   payment,
 	}
 
-	// Add RTO API status to response
-	if rtoSuccess {
-		response.Message += " - RTO drop API called successfully"
-	} else {
-		response.Message += " - RTO drop API call failed, but order placed"
-	}
+	// The RTO drop is delivered in the background by the outbox dispatcher
+	response.Message += " - RTO drop queued"
 
-	fmt.Printf("Sending response: %+v\n", response)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    response,
 	})
-
-	fmt.Printf("=== PLACE ORDER DEBUG END ===\n")
 }
 
-// callRTODropAPI calls the external RTO delete API
-func (h *OrderHandler) callRTODropAPI(userID, productID, catalogID string) bool {
-	fmt.Printf("=== RTO API DEBUG START ===\n")
-	fmt.Printf("Input parameters: userID=%s, productID=%s, catalogID=%s\n", userID, productID, catalogID)
+// claimIdempotencyKey either replays a stored response or claims the key and
+// starts recording the response written by the rest of the handler.
+// It returns false when the response has already been written.
+func (h *OrderHandler) claimIdempotencyKey(c *gin.Context, userID, key string, body []byte) (*services.IdempotencyLease, *responseRecorder, bool) {
+	if len(key) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Idempotency-Key must be at most 255 characters",
+		})
+		return nil, nil, false
+	}
 
-	// Get user code from user_mapping table
-	userCode, err := h.getUserCode(userID)
+	lease, record, err := h.idempotencyService.Begin(userID, key, services.HashRequest(body))
+	switch {
+	case errors.Is(err, services.ErrIdempotencyInFlight):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Order with this Idempotency-Key is still being processed",
+		})
+		return nil, nil, false
+	case errors.Is(err, services.ErrIdempotencyKeyReused):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"error":   "Idempotency-Key was already used with a different request",
+		})
+		return nil, nil, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to process Idempotency-Key",
+			"details": err.Error(),
+		})
+		return nil, nil, false
+	}
+
+	if record != nil {
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(record.ResponseCode, "application/json; charset=utf-8", []byte(record.ResponseBody))
+		return nil, nil, false
+	}
+
+	recorder := newResponseRecorder(c.Writer)
+	c.Writer = recorder
+	return lease, recorder, true
+}
+
+// completeIdempotencyKey stores the recorded response for later replays
+func (h *OrderHandler) completeIdempotencyKey(key string, lease *services.IdempotencyLease, recorder *responseRecorder) {
+	var err error
+	if recorder.body.Len() == 0 {
+		// Nothing was written (e.g. the handler panicked), let the client retry
+		err = h.idempotencyService.Release(lease)
+	} else {
+		err = h.idempotencyService.Complete(lease, recorder.Status(), recorder.body.Bytes())
+	}
 	if err != nil {
-		fmt.Printf("Warning: Failed to get user code for RTO API call: %v\n", err)
+		fmt.Printf("Warning: Failed to finalize Idempotency-Key %s: %v\n", key, err)
+	}
+}
+
+// writeUnitClaimError writes the response for an order or hold whose RTO unit
+// could not be claimed and reports whether err was such an error
+func writeUnitClaimError(c *gin.Context, err error, data interface{}) bool {
+	status, message := 0, ""
+	switch {
+	case errors.Is(err, services.ErrNoRTOCode):
+		status, message = http.StatusUnprocessableEntity, "No RTO location holds this item"
+	case errors.Is(err, services.ErrProductNotFound):
+		status, message = http.StatusNotFound, "Product not found"
+	case errors.Is(err, services.ErrRTOUnitNotFound):
+		status, message = http.StatusConflict, "Item is no longer available"
+	case errors.Is(err, services.ErrRTOUnitExpired):
+		status, message = http.StatusConflict, "Item is no longer listed"
+	case errors.Is(err, services.ErrAlreadyReserved):
+		status, message = http.StatusConflict, "Item is reserved by another buyer"
+	case errors.Is(err, services.ErrAlreadySold):
+		status, message = http.StatusConflict, "Item already sold"
+	default:
 		return false
 	}
 
-	fmt.Printf("Retrieved user code: %s\n", userCode)
+	response := gin.H{
+		"success": false,
+		"error":   message,
+		"details": err.Error(),
+	}
+	if data != nil {
+		response["data"] = data
+	}
+	c.JSON(status, response)
+	return true
+}
 
-	// Convert product_id and catalog_id to integers
-	productIDInt := h.parseProductID(productID)
-	catalogIDInt := h.parseCatalogID(catalogID)
+// getUserCode fetches the user's code from user_mapping table
+func (h *OrderHandler) getUserCode(userID string) (string, error) {
+	var userMapping models.UserMapping
 
-	fmt.Printf("Parsed IDs: productIDInt=%d, catalogIDInt=%d\n", productIDInt, catalogIDInt)
+	// Use the database directly from configs
+	result := configs.DB.Where("user_id = ?", userID).First(&userMapping)
+	if result.Error != nil {
+		return "", fmt.Errorf("failed to find user mapping for user_id %s: %w", userID, result.Error)
+	}
 
-	// Prepare request body
-	requestBody := RTODeleteRequest{
-		Code:      userCode,
-		ProductID: productIDInt,
-		CatalogID: catalogIDInt,
+	if userMapping.Code == "" {
+		return "", fmt.Errorf("no code found for user_id %s", userID)
 	}
+	return userMapping.Code, nil
+}
 
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		fmt.Printf("Error marshaling RTO delete request: %v\n", err)
-		return false
+// generateOrderID generates a unique, time-sortable order ID
+func (h *OrderHandler) generateOrderID() string {
+	return h.orderIDGenerator.NextID()
+}
+
+// ReserveItem holds an RTO unit for the user while they complete checkout
+func (h *OrderHandler) ReserveItem(c *gin.Context) {
+	var req ReserveItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	fmt.Printf("RTO API request body: %s\n", string(jsonData))
+	userID := middleware.CurrentUserID(c)
 
-	// Call external RTO delete API
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	rtoCode := req.RTOCode
+	if rtoCode == "" {
+		userCode, err := h.getUserCode(userID)
+		if err != nil {
+			fmt.Printf("Warning: Failed to get user code: %v\n", err)
+		}
+		rtoCode = userCode
 	}
 
-	req, err := http.NewRequest("DELETE", "http://localhost:3001/rto/delete-by-product", bytes.NewBuffer(jsonData))
+	reservation, err := h.orderService.HoldItem(userID, models.OrderItem{
+		ProductID: req.ProductID,
+		CatalogID: req.CatalogID,
+		RTOCode:   rtoCode,
+	})
+	if writeUnitClaimError(c, err, nil) {
+		return
+	}
 	if err != nil {
-		fmt.Printf("Error creating RTO delete request: %v\n", err)
-		return false
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to reserve item",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reservation,
+	})
+}
 
-	resp, err := client.Do(req)
+// ReleaseReservation gives up a hold before its TTL elapses
+func (h *OrderHandler) ReleaseReservation(c *gin.Context) {
+	reservationID := c.Param("reservation_id")
+
+	err := h.reservationService.Release(middleware.CurrentUserID(c), reservationID)
+	if errors.Is(err, services.ErrReservationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Reservation not found",
+		})
+		return
+	}
 	if err != nil {
-		fmt.Printf("Error calling RTO delete API: %v\n", err)
-		return false
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to release reservation",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reservation released",
+	})
+}
+
+// GetOrder returns a persisted order with its items
+func (h *OrderHandler) GetOrder(c *gin.Context) {
+	orderID := c.Param("order_id")
+
+	order, ok := h.loadOwnedOrder(c, orderID)
+	if !ok {
+		return
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	rtoDropEvents, err := h.outboxService.GetEventsByOrderID(orderID)
 	if err != nil {
-		fmt.Printf("Error reading RTO delete response: %v\n", err)
-		return false
+		fmt.Printf("Warning: Failed to get outbox events for order %s: %v\n", orderID, err)
 	}
 
-	if resp.StatusCode == http.StatusOK {
-		fmt.Printf("RTO delete API call successful: %s\n", string(body))
-		fmt.Printf("=== RTO API DEBUG END (SUCCESS) ===\n")
-		return true
-	} else {
-		fmt.Printf("RTO delete API call failed with status %d: %s\n", resp.StatusCode, string(body))
-		fmt.Printf("=== RTO API DEBUG END (FAILED) ===\n")
-		return false
+	payments, err := h.paymentService.GetPaymentsByOrderID(orderID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to get payments for order %s: %v\n", orderID, err)
 	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"data":            order,
+		"payments":        payments,
+		"rto_drop_events": rtoDropEvents,
+	})
 }
 
-// getUserCode fetches the user's code from user_mapping table
-func (h *OrderHandler) getUserCode(userID string) (string, error) {
-	fmt.Printf("Getting user code for userID: %s\n", userID)
+// CancelOrder cancels an order and returns its units to the RTO pool
+func (h *OrderHandler) CancelOrder(c *gin.Context) {
+	orderID := c.Param("order_id")
 
-	var userMapping models.UserMapping
+	// The body is optional; it only carries the cancellation reason
+	var req CancelOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
 
-	// Use the database directly from configs
-	result := configs.DB.Where("user_id = ?", userID).First(&userMapping)
-	if result.Error != nil {
-		fmt.Printf("Database error: %v\n", result.Error)
-		return "", fmt.Errorf("failed to find user mapping for user_id %s: %w", userID, result.Error)
+	order, err := h.orderService.CancelOrder(orderID, middleware.CurrentUserID(c), req.Reason)
+	switch {
+	case errors.Is(err, services.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Order not found",
+		})
+		return
+	case errors.Is(err, services.ErrInvalidOrderTransition):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Order can no longer be cancelled",
+			"details": err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to cancel order",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	fmt.Printf("Found user mapping: %+v\n", userMapping)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Order cancelled successfully",
+		"data":    order,
+	})
+}
 
-	if userMapping.Code == "" {
-		fmt.Printf("No code found for user %s\n", userID)
-		return "", fmt.Errorf("no code found for user_id %s", userID)
+// GetOrderHistory returns the status history of an order
+func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
+	orderID := c.Param("order_id")
+
+	if _, ok := h.loadOwnedOrder(c, orderID); !ok {
+		return
 	}
 
-	fmt.Printf("Found code '%s' for user %s\n", userMapping.Code, userID)
-	return userMapping.Code, nil
-}
+	history, err := h.orderService.GetStatusHistory(orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch order history",
+			"details": err.Error(),
+		})
+		return
+	}
 
-// generateOrderID generates a unique order ID
-func (h *OrderHandler) generateOrderID() string {
-	timestamp := time.Now().Unix()
-	return fmt.Sprintf("MEESH%d", timestamp)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    history,
+	})
 }
 
-// parseProductID converts product ID string to integer
-func (h *OrderHandler) parseProductID(productID string) int {
-	// Remove 's-' prefix if present
-	if len(productID) > 2 && productID[:2] == "s-" {
-		productID = productID[2:]
+// GetInvoice returns the invoice for an order as JSON, or as a PDF download
+// when requested with ?format=pdf or an Accept: application/pdf header
+func (h *OrderHandler) GetInvoice(c *gin.Context) {
+	orderID := c.Param("order_id")
+
+	if _, ok := h.loadOwnedOrder(c, orderID); !ok {
+		return
 	}
 
-	// Try to parse as integer, return fallback if failed
-	var result int
-	_, err := fmt.Sscanf(productID, "%d", &result)
+	invoice, err := h.invoiceService.GetInvoice(orderID)
 	if err != nil {
-		return 67890 // Fallback value
+		switch {
+		case errors.Is(err, services.ErrOrderNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "Order not found",
+				"details": err.Error(),
+			})
+		case errors.Is(err, services.ErrInvoiceNotAvailable):
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error":   "Invoice is not available for this order yet",
+				"details": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "Failed to load invoice",
+				"details": err.Error(),
+			})
+		}
+		return
 	}
-	return result
+
+	if c.Query("format") == "pdf" || strings.Contains(c.GetHeader("Accept"), "application/pdf") {
+		filename := strings.ReplaceAll(invoice.InvoiceNumber, "/", "-") + ".pdf"
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Data(http.StatusOK, "application/pdf", services.RenderInvoicePDF(invoice))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    invoice,
+	})
 }
 
-// parseCatalogID converts catalog ID string to integer
-func (h *OrderHandler) parseCatalogID(catalogID string) int {
-	// Try to parse as integer, return fallback if failed
-	var result int
-	_, err := fmt.Sscanf(catalogID, "%d", &result)
+// loadOwnedOrder fetches an order of the authenticated user.
+// Orders of other users are reported as not found so their IDs are not confirmed.
+func (h *OrderHandler) loadOwnedOrder(c *gin.Context, orderID string) (*models.Order, bool) {
+	order, err := h.orderService.GetOrderByID(orderID)
+	if err == nil && order.UserID != middleware.CurrentUserID(c) {
+		err = services.ErrOrderNotFound
+	}
 	if err != nil {
-		return 12345 // Fallback value
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Order not found",
+			"details": err.Error(),
+		})
+		return nil, false
 	}
-	return result
+	return order, true
+}
+
+// GetUserOrders returns all orders placed by a user
+func (h *OrderHandler) GetUserOrders(c *gin.Context) {
+	userID, ok := authorizeUserParam(c)
+	if !ok {
+		return
+	}
+
+	orders, err := h.orderService.GetOrdersByUserID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to fetch orders",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    orders,
+		"total":   len(orders),
+	})
 }
 
 // HealthCheck provides health check for order service
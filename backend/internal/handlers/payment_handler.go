@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"meesho-clone/internal/middleware"
+	"meesho-clone/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentHandler handles payment confirmation and provider callbacks
+type PaymentHandler struct {
+	paymentService *services.PaymentService
+}
+
+// NewPaymentHandler creates a new payment handler
+func NewPaymentHandler() *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: services.NewPaymentService(),
+	}
+}
+
+// ConfirmPayment completes a pending payment the customer has authorised
+func (h *PaymentHandler) ConfirmPayment(c *gin.Context) {
+	payment, err := h.paymentService.ConfirmPayment(c.Param("payment_id"), middleware.CurrentUserID(c))
+	if errors.Is(err, services.ErrPaymentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Payment not found",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrPaymentNotConfirmable) {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"error":   "Only online payments can be confirmed",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to confirm payment",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    payment,
+	})
+}
+
+// Webhook receives signed payment status callbacks from a provider
+func (h *PaymentHandler) Webhook(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "Failed to read webhook body",
+		})
+		return
+	}
+
+	payment, err := h.paymentService.HandleWebhook(c.Param("provider"), payload, c.GetHeader("X-Payment-Signature"))
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhookSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error":   "Invalid webhook signature",
+		})
+		return
+	case errors.Is(err, services.ErrUnknownPaymentProvider), errors.Is(err, services.ErrWebhookNotSupported):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Unknown payment provider",
+		})
+		return
+	case errors.Is(err, services.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"error":   "Payment not found",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "Failed to process webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    payment,
+	})
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"meesho-clone/internal/middleware"
 	"meesho-clone/internal/services"
 
 	"github.com/gin-gonic/gin"
@@ -34,26 +35,7 @@ func (h *ProductHandler) GetProductDetails(c *gin.Context) {
 		return
 	}
 
-	// Get user_id from query parameter
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error":   "user_id is required as query parameter",
-		})
-		return
-	}
-
-	// Validate that user exists
-	_, err := h.userService.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "User not found",
-			"details": "user not found",
-		})
-		return
-	}
+	userID := middleware.CurrentUserID(c)
 
 	// Get product details
 	productResponse, err := h.productService.GetProductDetails(productID, userID)
@@ -81,26 +63,7 @@ func (h *ProductHandler) GetProductDetailsByID(c *gin.Context) {
 		return
 	}
 
-	// Get user_id from query parameter
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"error":   "user_id is required as query parameter",
-		})
-		return
-	}
-
-	// Validate that user exists
-	_, err := h.userService.GetUserByID(userID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"error":   "User not found",
-			"details": "user not found",
-		})
-		return
-	}
+	userID := middleware.CurrentUserID(c)
 
 	// Get product details
 	productResponse, err := h.productService.GetProductDetails(productID, userID)
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"bytes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseRecorder tees everything written to the client into a buffer
+type responseRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// newResponseRecorder wraps a gin response writer
+func newResponseRecorder(w gin.ResponseWriter) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		body:           &bytes.Buffer{},
+	}
+}
+
+// Write records and forwards the response body
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// WriteString records and forwards the response body
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
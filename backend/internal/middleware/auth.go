@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"errors"
+	"meesho-clone/internal/models"
+	"meesho-clone/internal/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// contextUserKey holds the authenticated *models.User
+	contextUserKey = "auth_user"
+
+	// contextClaimsKey holds the verified *services.TokenClaims
+	contextClaimsKey = "auth_claims"
+)
+
+// RequireAuth rejects requests without a valid bearer access token or whose
+// session was logged out, and puts the authenticated user in the gin context
+func RequireAuth(tokenService *services.TokenService, sessionService *services.SessionService, userService *services.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "Authentication required",
+				"details": "missing bearer token in Authorization header",
+			})
+			return
+		}
+
+		claims, err := tokenService.ParseAccessToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "Invalid or expired access token",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		// Checked on every request so logging out takes effect immediately
+		if _, err := sessionService.ValidateSession(claims.FamilyID, claims.Subject, c.ClientIP()); err != nil {
+			if errors.Is(err, services.ErrSessionRevoked) || errors.Is(err, services.ErrSessionNotFound) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"success": false,
+					"error":   "Session has ended. Please log in again",
+					"details": err.Error(),
+				})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"error":   "Failed to validate session",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		user, err := userService.GetUserByID(claims.Subject)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "User not found",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.Set(contextUserKey, user)
+		c.Set(contextClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// OptionalAuth authenticates requests that carry a bearer token, exactly as
+// RequireAuth does, and lets requests without one through anonymously
+func OptionalAuth(tokenService *services.TokenService, sessionService *services.SessionService, userService *services.UserService) gin.HandlerFunc {
+	requireAuth := RequireAuth(tokenService, sessionService, userService)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		requireAuth(c)
+	}
+}
+
+// RequirePermission rejects requests whose authenticated user lacks any of the
+// permissions. It must run after RequireAuth.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "Authentication required",
+			})
+			return
+		}
+
+		for _, permission := range permissions {
+			if !models.RoleHasPermission(user.Role, permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"success": false,
+					"error":   "You do not have permission to perform this action",
+					"details": "missing permission " + permission,
+				})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// CurrentUser returns the user authenticated by RequireAuth, or nil
+func CurrentUser(c *gin.Context) *models.User {
+	value, exists := c.Get(contextUserKey)
+	if !exists {
+		return nil
+	}
+	user, _ := value.(*models.User)
+	return user
+}
+
+// CurrentUserID returns the ID of the authenticated user, or an empty string
+func CurrentUserID(c *gin.Context) string {
+	if user := CurrentUser(c); user != nil {
+		return user.UserID
+	}
+	return ""
+}
+
+// CurrentSessionID returns the session of the authenticated request, or an empty string
+func CurrentSessionID(c *gin.Context) string {
+	if claims := CurrentClaims(c); claims != nil {
+		return claims.FamilyID
+	}
+	return ""
+}
+
+// CurrentClaims returns the verified access token claims, or nil
+func CurrentClaims(c *gin.Context) *services.TokenClaims {
+	value, exists := c.Get(contextClaimsKey)
+	if !exists {
+		return nil
+	}
+	claims, _ := value.(*services.TokenClaims)
+	return claims
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKeyFunc picks the bucket a request counts against.
+// An empty key exempts the request from the policy.
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitPolicy allows Limit requests per Period for each key, refilled
+// evenly so that short bursts up to Limit are allowed
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Key    RateLimitKeyFunc
+}
+
+// KeyByIP limits each client IP
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByUser limits each authenticated user, falling back to the client IP.
+// It must run after RequireAuth to see the user.
+func KeyByUser(c *gin.Context) string {
+	if userID := CurrentUserID(c); userID != "" {
+		return "user:" + userID
+	}
+	return KeyByIP(c)
+}
+
+// KeyByPhone limits each phone number named by the request, read from the
+// phone_number query parameter or JSON body field. The body is read whatever
+// its Content-Type, since the handlers bind it as JSON regardless.
+func KeyByPhone(c *gin.Context) string {
+	if phone := c.Query("phone_number"); phone != "" {
+		return phoneKey(phone)
+	}
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	// The body is restored so the handler can still bind it
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		PhoneNumber string `json:"phone_number"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return ""
+	}
+	return phoneKey(payload.PhoneNumber)
+}
+
+// phoneKey reduces a phone number to its 10 national digits so that
+// formatting variants such as "+91 98765 43210" share one bucket
+func phoneKey(phone string) string {
+	digits := make([]rune, 0, len(phone))
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+
+	normalised := string(digits)
+	switch {
+	case len(normalised) == 12 && strings.HasPrefix(normalised, "91"):
+		normalised = normalised[2:]
+	case len(normalised) == 11 && strings.HasPrefix(normalised, "0"):
+		normalised = normalised[1:]
+	}
+	if normalised == "" {
+		return ""
+	}
+	return "phone:" + normalised
+}
+
+// RateLimit rejects requests that exceed any of the policies with 429 Too
+// Many Requests. The X-RateLimit-* headers describe the policy closest to its
+// limit. If the store fails the request is let through rather than blocking
+// all traffic.
+func RateLimit(store RateLimitStore, policies ...RateLimitPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reported *RateLimitResult
+		var reportedPolicy RateLimitPolicy
+		var rejected bool
+		var retryAfter time.Duration
+
+		for _, policy := range policies {
+			key := policy.Key(c)
+			if key == "" {
+				continue
+			}
+
+			result, err := store.Take(c.Request.Context(), policy.Name+":"+key, policy.Limit, policy.Period)
+			if err != nil {
+				fmt.Printf("Warning: Rate limit check %s failed: %v\n", policy.Name, err)
+				continue
+			}
+
+			if !result.Allowed {
+				rejected = true
+				if result.RetryAfter > retryAfter {
+					retryAfter = result.RetryAfter
+				}
+			}
+			if reported == nil || result.Remaining < reported.Remaining || (!result.Allowed && reported.Allowed) {
+				r := result
+				reported = &r
+				reportedPolicy = policy
+			}
+		}
+
+		if reported != nil {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(reportedPolicy.Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(reported.Remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(reported.ResetAfter).Unix(), 10))
+		}
+
+		if rejected {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			if seconds < 1 {
+				seconds = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"error":   "Too many requests. Please try again later",
+				"details": fmt.Sprintf("retry after %d seconds", seconds),
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitResult is the outcome of taking a token from a bucket
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// RateLimitStore keeps token buckets. Implementations must be safe for
+// concurrent use; a shared store lets several instances enforce one limit.
+type RateLimitStore interface {
+	// Take removes a token from the bucket for key, which holds up to limit
+	// tokens and refills completely over period
+	Take(ctx context.Context, key string, limit int, period time.Duration) (RateLimitResult, error)
+}
+
+// bucketResult derives the result of a take from the tokens left in the bucket
+func bucketResult(allowed bool, tokens float64, limit int, period time.Duration) RateLimitResult {
+	refill := period / time.Duration(limit)
+	result := RateLimitResult{
+		Allowed:    allowed,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration((float64(limit) - tokens) * float64(refill)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(refill))
+	}
+	return result
+}
+
+// memoryBucket is a token bucket held in process memory
+type memoryBucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. Limits are per
+// instance, so it suits a single server or local development.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit int, period time.Duration) (RateLimitResult, error) {
+	now := time.Now()
+	refill := period / time.Duration(limit)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit), updated: now, period: period}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(float64(limit), bucket.tokens+float64(now.Sub(bucket.updated))/float64(refill))
+	bucket.updated = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	return bucketResult(allowed, bucket.tokens, limit, period), nil
+}
+
+// sweep drops buckets that have been idle long enough to have refilled,
+// since a missing bucket starts full anyway
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.updated) > bucket.period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RedisScripter is the subset of a Redis client needed by RedisRateLimitStore.
+// It matches the shape of Eval in common Redis clients, so a thin adapter is
+// enough to plug one in.
+type RedisScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// redisTokenBucketScript refills and takes from a bucket atomically.
+// Tokens are returned as a string because Redis truncates Lua numbers to integers.
+const redisTokenBucketScript = `
+local limit = tonumber(ARGV[1])
+local refill_ms = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or limit
+local updated = tonumber(state[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - updated) / refill_ms)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(limit * refill_ms))
+return {allowed, tostring(tokens)}
+`
+
+// RedisRateLimitStore keeps buckets in Redis so limits hold across instances
+type RedisRateLimitStore struct {
+	client RedisScripter
+	prefix string
+}
+
+// NewRedisRateLimitStore creates a store that keeps buckets under the key prefix
+func NewRedisRateLimitStore(client RedisScripter, prefix string) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, prefix: prefix}
+}
+
+// Take implements RateLimitStore
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, limit int, period time.Duration) (RateLimitResult, error) {
+	refill := period / time.Duration(limit)
+	reply, err := s.client.Eval(ctx, redisTokenBucketScript, []string{s.prefix + key},
+		limit, refill.Milliseconds(), time.Now().UnixMilli())
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate limit script failed: %w", err)
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	tokensText, ok := values[1].(string)
+	if !ok {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	tokens, err := strconv.ParseFloat(tokensText, 64)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+
+	return bucketResult(allowed == 1, tokens, limit, period), nil
+}
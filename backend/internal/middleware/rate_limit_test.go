@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestKeyByPhone(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{name: "json", contentType: "application/json", body: `{"phone_number":"9876543210"}`, want: "phone:9876543210"},
+		{name: "text content type", contentType: "text/plain", body: `{"phone_number":"9876543210"}`, want: "phone:9876543210"},
+		{name: "country code", contentType: "application/json", body: `{"phone_number":"+91 98765 43210"}`, want: "phone:9876543210"},
+		{name: "trunk prefix", contentType: "application/json", body: `{"phone_number":"09876543210"}`, want: "phone:9876543210"},
+		{name: "no phone", contentType: "application/json", body: `{}`, want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			c.Request.Header.Set("Content-Type", tc.contentType)
+
+			if got := KeyByPhone(c); got != tc.want {
+				t.Fatalf("KeyByPhone() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"meesho-clone/internal/pii"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Address represents a delivery address in the addresses table
+type Address struct {
+	ID        uint           `json:"-" gorm:"primaryKey"`
+	AddressID string         `json:"address_id" gorm:"column:address_id;type:varchar(50);uniqueIndex:idx_address_id;not null"`
+	UserID    string         `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	Name      string         `json:"name" gorm:"column:name;type:varchar(100);not null"`
+	Phone     string         `json:"phone" gorm:"column:phone;type:varchar(255);serializer:encrypted;not null"`
+	Line1     string         `json:"line1" gorm:"column:line1;type:varchar(255);not null"`
+	Line2     string         `json:"line2,omitempty" gorm:"column:line2;type:varchar(255)"`
+	Landmark  string         `json:"landmark,omitempty" gorm:"column:landmark;type:varchar(255)"`
+	City      string         `json:"city" gorm:"column:city;type:varchar(100);not null"`
+	State     string         `json:"state" gorm:"column:state;type:varchar(100);not null"`
+	Pincode   string         `json:"pincode" gorm:"column:pincode;type:varchar(6);index;not null"`
+	IsDefault bool           `json:"is_default" gorm:"column:is_default;default:false"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName specifies the table name for Address
+func (Address) TableName() string {
+	return "addresses"
+}
+
+// MarshalJSON masks the contact phone number in every API response
+func (a Address) MarshalJSON() ([]byte, error) {
+	type address Address
+	masked := address(a)
+	masked.Phone = pii.MaskPhone(a.Phone)
+	return json.Marshal(masked)
+}
+
+// FormattedAddress returns the address as a single line for labels and receipts
+func (a Address) FormattedAddress() string {
+	parts := []string{a.Line1}
+	for _, part := range []string{a.Line2, a.Landmark, a.City, a.State} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ") + " - " + a.Pincode
+}
+
+// AddressRequest represents the payload for creating or updating an address
+type AddressRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	Phone     string `json:"phone" binding:"required,len=10,numeric"`
+	Line1     string `json:"line1" binding:"required,max=255"`
+	Line2     string `json:"line2" binding:"max=255"`
+	Landmark  string `json:"landmark" binding:"max=255"`
+	City      string `json:"city" binding:"required,max=100"`
+	State     string `json:"state" binding:"required,max=100"`
+	Pincode   string `json:"pincode" binding:"required,len=6,numeric"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// ServiceablePincode represents the serviceable_pincodes table structure.
+// Each row allows an RTO code to deliver to a pincode, or to every pincode
+// starting with the given prefix (e.g. "5600").
+type ServiceablePincode struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Code      string    `json:"code" gorm:"column:code;type:varchar(20);uniqueIndex:idx_serviceable_code_pincode;not null"`
+	Pincode   string    `json:"pincode" gorm:"column:pincode;type:varchar(6);uniqueIndex:idx_serviceable_code_pincode;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ServiceablePincode
+func (ServiceablePincode) TableName() string {
+	return "serviceable_pincodes"
+}
+
+// ServiceablePincodesRequest represents the request for setting the pincodes
+// and pincode prefixes an RTO code delivers to
+type ServiceablePincodesRequest struct {
+	Pincodes []string `json:"pincodes" binding:"required,dive,min=1,max=6,numeric"`
+}
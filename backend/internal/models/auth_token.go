@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken represents the refresh_tokens table structure.
+// Tokens issued from one login share a family; rotating a token marks it
+// used, and presenting a used token again revokes the whole family.
+type RefreshToken struct {
+	ID         uint       `json:"-" gorm:"primaryKey"`
+	TokenID    string     `json:"token_id" gorm:"column:token_id;type:varchar(50);uniqueIndex:idx_refresh_token_id;not null"`
+	FamilyID   string     `json:"family_id" gorm:"column:family_id;type:varchar(50);index;not null"`
+	UserID     string     `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty" gorm:"column:rotated_at"`
+	ReplacedBy string     `json:"replaced_by,omitempty" gorm:"column:replaced_by;type:varchar(50)"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// TokenPair is returned to clients on login and token refresh
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	TokenType        string    `json:"token_type"`
+}
+
+// RefreshTokenRequest represents the request for refreshing or revoking tokens
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+)
+
+// CartItem represents the cart_items table structure
+type CartItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"column:user_id;type:varchar(50);uniqueIndex:idx_cart_user_product;not null"`
+	ProductID string    `json:"product_id" gorm:"column:product_id;type:varchar(50);uniqueIndex:idx_cart_user_product;not null"`
+	CatalogID string    `json:"catalog_id" gorm:"column:catalog_id;type:varchar(50)"`
+	RTOCode   string    `json:"rto_code,omitempty" gorm:"column:rto_code;type:varchar(20)"`
+	Quantity  int       `json:"quantity" gorm:"column:quantity;not null"`
+	UnitPrice float64   `json:"unit_price" gorm:"column:unit_price;type:decimal(10,2)"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for CartItem
+func (CartItem) TableName() string {
+	return "cart_items"
+}
+
+// CartSummary represents a user's cart with its totals
+type CartSummary struct {
+	UserID     string     `json:"user_id"`
+	Items      []CartItem `json:"items"`
+	TotalItems int        `json:"total_items"`
+	Subtotal   float64    `json:"subtotal"`
+}
+
+// CheckoutRemovedItem describes a cart item that could not be ordered
+type CheckoutRemovedItem struct {
+	ProductID string `json:"product_id"`
+	CatalogID string `json:"catalog_id"`
+	Reason    string `json:"reason"`
+}
+
+// CheckoutPriceChange describes a cart item whose price changed since it was added
+type CheckoutPriceChange struct {
+	ProductID string  `json:"product_id"`
+	OldPrice  float64 `json:"old_price"`
+	NewPrice  float64 `json:"new_price"`
+}
+
+// CheckoutResult represents the outcome of turning a cart into an order
+type CheckoutResult struct {
+	Order        *Order                `json:"order"`
+	RemovedItems []CheckoutRemovedItem `json:"removed_items"`
+	PriceChanges []CheckoutPriceChange `json:"price_changes"`
+}
@@ -29,8 +29,31 @@ type CatalogProduct struct {
 	OriginalPrice   string `json:"original_price,omitempty"`
 	Discount        string `json:"discount,omitempty"`
 	DiscountPercent int    `json:"discount_percent,omitempty"`
+
+	// Set for RTO units: the code holding the unit on offer, to be sent back
+	// when ordering, reserving or adding the product to the cart
+	RTOCode string `json:"rto_code,omitempty"`
+
+	// Set for RTO units with an age policy: the markdown already taken off
+	// Price, and when the unit stops being listed
+	AgeMarkdownPercent int        `json:"age_markdown_percent,omitempty"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	ExpiresIn          string     `json:"expires_in,omitempty"`
 }
 
+// Catalog sources, one per tier of the fallback chain that can serve a user.
+// The global tier serves the computed trending catalogs, or the snapshot
+// before any have been computed.
+const (
+	CatalogSourceUserCode         = "rto_api_with_ranking"
+	CatalogSourceCityNeighbours   = "rto_city_neighbours_with_ranking"
+	CatalogSourceStateCodes       = "rto_state_codes_with_ranking"
+	CatalogSourceCityTrending     = "trending_city_with_ranking"
+	CatalogSourceCategoryTrending = "trending_category_with_ranking"
+	CatalogSourceGlobal           = "fallback_catalog_ids_with_ranking"
+	CatalogSourceSnapshot         = "trending_snapshot_with_ranking"
+)
+
 // CatalogMeta represents metadata about the catalog response
 type CatalogMeta struct {
 	TotalProducts int       `json:"total_products"`
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Idempotency record statuses
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyKey represents the idempotency_keys table structure.
+// It stores the first response returned for a (user_id, key) pair so that
+// retried requests can be answered without repeating their side effects.
+// An in-progress claim is only held until LockedUntil, so a request that died
+// mid-way does not block its key for the whole window. Each claim bumps
+// LeaseGeneration, which fences off the request that held the lease before.
+type IdempotencyKey struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          string     `json:"user_id" gorm:"column:user_id;type:varchar(50);uniqueIndex:idx_idempotency_user_key;not null"`
+	IdempotencyKey  string     `json:"idempotency_key" gorm:"column:idempotency_key;type:varchar(255);uniqueIndex:idx_idempotency_user_key;not null"`
+	RequestHash     string     `json:"request_hash" gorm:"column:request_hash;type:varchar(64)"`
+	Status          string     `json:"status" gorm:"column:status;type:varchar(20);not null"`
+	ResponseCode    int        `json:"response_code" gorm:"column:response_code"`
+	ResponseBody    string     `json:"response_body" gorm:"column:response_body;type:mediumtext"`
+	LockedUntil     *time.Time `json:"locked_until,omitempty" gorm:"column:locked_until"`
+	LeaseGeneration int        `json:"lease_generation" gorm:"column:lease_generation;not null;default:1"`
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"column:expires_at;index"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for IdempotencyKey
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
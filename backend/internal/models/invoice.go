@@ -0,0 +1,97 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// InvoiceLine represents one line item on an invoice
+type InvoiceLine struct {
+	ProductID           string  `json:"product_id"`
+	CatalogID           string  `json:"catalog_id"`
+	Description         string  `json:"description"`
+	Quantity            int     `json:"quantity"`
+	SupplierListedPrice float64 `json:"supplier_listed_price"`
+	Shipping            float64 `json:"shipping"`
+	Discount            float64 `json:"discount"`
+	TaxableValue        float64 `json:"taxable_value"`
+	GSTRate             float64 `json:"gst_rate"`
+	CGST                float64 `json:"cgst"`
+	SGST                float64 `json:"sgst"`
+	Total               float64 `json:"total"`
+}
+
+// InvoiceLines is a custom type to handle JSON serialization/deserialization
+type InvoiceLines []InvoiceLine
+
+// Value implements the driver.Valuer interface
+func (l InvoiceLines) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return json.Marshal(l)
+}
+
+// Scan implements the sql.Scanner interface
+func (l *InvoiceLines) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("cannot scan non-string/non-byte value into InvoiceLines")
+	}
+
+	return json.Unmarshal(bytes, l)
+}
+
+// Invoice represents the invoices table structure.
+// Invoices are immutable once issued, except that the billing details are
+// scrubbed when the account is anonymised; the lines are stored as JSON.
+type Invoice struct {
+	ID             uint         `json:"-" gorm:"primaryKey"`
+	InvoiceNumber  string       `json:"invoice_number" gorm:"column:invoice_number;type:varchar(50);uniqueIndex:idx_invoice_number;not null"`
+	FinancialYear  string       `json:"financial_year" gorm:"column:financial_year;type:varchar(7);not null"`
+	Sequence       int          `json:"sequence" gorm:"column:sequence;not null"`
+	OrderID        string       `json:"order_id" gorm:"column:order_id;type:varchar(50);uniqueIndex:idx_invoice_order_id;not null"`
+	UserID         string       `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	BillingName    string       `json:"billing_name" gorm:"column:billing_name;type:varchar(100)"`
+	BillingAddress string       `json:"billing_address" gorm:"column:billing_address;type:text"`
+	PaymentMethod  string       `json:"payment_method" gorm:"column:payment_method;type:varchar(20)"`
+	Lines          InvoiceLines `json:"lines" gorm:"column:lines;type:json"`
+	Subtotal       float64      `json:"subtotal" gorm:"column:subtotal;type:decimal(10,2)"`
+	ShippingTotal  float64      `json:"shipping_total" gorm:"column:shipping_total;type:decimal(10,2)"`
+	DiscountTotal  float64      `json:"discount_total" gorm:"column:discount_total;type:decimal(10,2)"`
+	TaxableValue   float64      `json:"taxable_value" gorm:"column:taxable_value;type:decimal(10,2)"`
+	CGSTTotal      float64      `json:"cgst_total" gorm:"column:cgst_total;type:decimal(10,2)"`
+	SGSTTotal      float64      `json:"sgst_total" gorm:"column:sgst_total;type:decimal(10,2)"`
+	GrandTotal     float64      `json:"grand_total" gorm:"column:grand_total;type:decimal(10,2)"`
+	OrderPlacedAt  time.Time    `json:"order_placed_at" gorm:"column:order_placed_at"`
+	IssuedAt       time.Time    `json:"issued_at" gorm:"column:issued_at"`
+}
+
+// TableName specifies the table name for Invoice
+func (Invoice) TableName() string {
+	return "invoices"
+}
+
+// InvoiceSequence represents the invoice_sequences table structure.
+// It holds the last invoice number issued in each financial year.
+type InvoiceSequence struct {
+	FinancialYear string `gorm:"column:financial_year;type:varchar(7);primaryKey"`
+	LastValue     int    `gorm:"column:last_value;not null"`
+}
+
+// TableName specifies the table name for InvoiceSequence
+func (InvoiceSequence) TableName() string {
+	return "invoice_sequences"
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"meesho-clone/internal/pii"
+	"time"
+)
+
+// Order represents the orders table structure.
+// The shipping fields are a snapshot of the delivery address at order time.
+type Order struct {
+	ID              uint        `json:"id" gorm:"primaryKey"`
+	OrderID         string      `json:"order_id" gorm:"column:order_id;type:varchar(50);uniqueIndex:idx_order_id;not null"`
+	UserID          string      `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	UserCode        string      `json:"user_code" gorm:"column:user_code;type:varchar(20)"`
+	Status          string      `json:"status" gorm:"column:status;type:varchar(30);index;not null;default:'PLACED'"`
+	TotalAmount     float64     `json:"total_amount" gorm:"column:total_amount;type:decimal(10,2)"`
+	RTODropStatus   string      `json:"rto_drop_status" gorm:"column:rto_drop_status;type:varchar(20)"`
+	AddressID       string      `json:"address_id" gorm:"column:address_id;type:varchar(50)"`
+	ShippingName    string      `json:"shipping_name" gorm:"column:shipping_name;type:varchar(100)"`
+	ShippingPhone   string      `json:"shipping_phone" gorm:"column:shipping_phone;type:varchar(255);serializer:encrypted"`
+	ShippingAddress string      `json:"shipping_address" gorm:"column:shipping_address;type:text"`
+	ShippingPincode string      `json:"shipping_pincode" gorm:"column:shipping_pincode;type:varchar(6)"`
+	Items           []OrderItem `json:"items" gorm:"foreignKey:OrderID;references:OrderID"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// TableName specifies the table name for Order
+func (Order) TableName() string {
+	return "orders"
+}
+
+// MarshalJSON masks the shipping phone number in every API response
+func (o Order) MarshalJSON() ([]byte, error) {
+	type order Order
+	masked := order(o)
+	masked.ShippingPhone = pii.MaskPhone(o.ShippingPhone)
+	return json.Marshal(masked)
+}
+
+// OrderItem represents the order_items table structure.
+// Each item is one RTO unit, identified by the code it was sold from and its
+// sub order number; the unit's original order date is kept so a cancelled
+// unit goes back into the RTO list with its age. UnitPrice is the supplier
+// listed price; the buyer is charged it less MarkdownPercent.
+type OrderItem struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	OrderID         string    `json:"order_id" gorm:"column:order_id;type:varchar(50);index;not null"`
+	ProductID       string    `json:"product_id" gorm:"column:product_id;type:varchar(50)"`
+	CatalogID       string    `json:"catalog_id" gorm:"column:catalog_id;type:varchar(50)"`
+	Quantity        int       `json:"quantity" gorm:"column:quantity"`
+	UnitPrice       float64   `json:"unit_price" gorm:"column:unit_price;type:decimal(10,2)"`
+	MarkdownPercent int       `json:"markdown_percent,omitempty" gorm:"column:markdown_percent;not null;default:0"`
+	RTOCode         string    `json:"rto_code,omitempty" gorm:"column:rto_code;type:varchar(20)"`
+	SubOrderNum     string    `json:"sub_order_num,omitempty" gorm:"column:sub_order_num;type:varchar(50)"`
+	RTOOrderDate    string    `json:"rto_order_date,omitempty" gorm:"column:rto_order_date;type:varchar(10)"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for OrderItem
+func (OrderItem) TableName() string {
+	return "order_items"
+}
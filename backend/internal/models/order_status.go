@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+)
+
+// Order statuses
+const (
+	OrderStatusPlaced         = "PLACED"
+	OrderStatusPendingPayment = "PENDING_PAYMENT"
+	OrderStatusConfirmed      = "CONFIRMED"
+	OrderStatusShipped        = "SHIPPED"
+	OrderStatusOutForDelivery = "OUT_FOR_DELIVERY"
+	OrderStatusDelivered      = "DELIVERED"
+	OrderStatusCancelled      = "CANCELLED"
+	OrderStatusReturned       = "RETURNED"
+)
+
+// orderStatusTransitions lists the statuses each status may move to
+var orderStatusTransitions = map[string][]string{
+	OrderStatusPlaced:         {OrderStatusPendingPayment, OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusPendingPayment: {OrderStatusConfirmed, OrderStatusCancelled},
+	OrderStatusConfirmed:      {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:        {OrderStatusOutForDelivery, OrderStatusReturned},
+	OrderStatusOutForDelivery: {OrderStatusDelivered, OrderStatusReturned},
+	OrderStatusDelivered:      {OrderStatusReturned},
+}
+
+// IsValidOrderStatus reports whether status is a known order status
+func IsValidOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusPlaced, OrderStatusPendingPayment, OrderStatusConfirmed, OrderStatusShipped, OrderStatusOutForDelivery,
+		OrderStatusDelivered, OrderStatusCancelled, OrderStatusReturned:
+		return true
+	}
+	return false
+}
+
+// CanTransitionOrderStatus reports whether an order may move from one status to another
+func CanTransitionOrderStatus(from, to string) bool {
+	for _, allowed := range orderStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatusHistory represents the order_status_history table structure
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OrderID    string    `json:"order_id" gorm:"column:order_id;type:varchar(50);index;not null"`
+	FromStatus string    `json:"from_status" gorm:"column:from_status;type:varchar(30)"`
+	ToStatus   string    `json:"to_status" gorm:"column:to_status;type:varchar(30);not null"`
+	Actor      string    `json:"actor" gorm:"column:actor;type:varchar(100)"`
+	Reason     string    `json:"reason,omitempty" gorm:"column:reason;type:varchar(255)"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for OrderStatusHistory
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// OTP purposes. A code is only accepted for the purpose it was sent for.
+const (
+	OTPPurposeLogin          = "login"
+	OTPPurposePhoneChangeOld = "phone_change_old"
+	OTPPurposePhoneChangeNew = "phone_change_new"
+)
+
+// OTPChallenge represents the otp_challenges table structure.
+// Only keyed hashes of the code and the phone number are stored; rows are also
+// used to throttle sends and are purged once no throttle window needs them.
+type OTPChallenge struct {
+	ID          uint       `json:"-" gorm:"primaryKey"`
+	ChallengeID string     `json:"challenge_id" gorm:"column:challenge_id;type:varchar(50);uniqueIndex:idx_challenge_id;not null"`
+	PhoneHash   string     `json:"-" gorm:"column:phone_hash;type:varchar(64);index:idx_otp_phone_created;not null"`
+	Purpose     string     `json:"purpose" gorm:"column:purpose;type:varchar(20);not null;default:'login'"`
+	CodeHash    string     `json:"-" gorm:"column:code_hash;type:varchar(64);not null"`
+	Attempts    int        `json:"attempts" gorm:"column:attempts;not null;default:0"`
+	RequestIP   string     `json:"-" gorm:"column:request_ip;type:varchar(45);index:idx_otp_ip_created"`
+	ExpiresAt   time.Time  `json:"expires_at" gorm:"column:expires_at;not null"`
+	ConsumedAt  *time.Time `json:"consumed_at,omitempty" gorm:"column:consumed_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"index:idx_otp_phone_created;index:idx_otp_ip_created"`
+}
+
+// TableName specifies the table name for OTPChallenge
+func (OTPChallenge) TableName() string {
+	return "otp_challenges"
+}
+
+// OTPRequest represents the request for sending a login OTP
+type OTPRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required,min=10,max=10"`
+}
+
+// OTPVerifyRequest represents the request for verifying a login OTP
+type OTPVerifyRequest struct {
+	PhoneNumber string `json:"phone_number" binding:"required,min=10,max=10"`
+	Code        string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// PhoneChangeRequest represents the request for starting a phone number change
+type PhoneChangeRequest struct {
+	NewPhoneNumber string `json:"new_phone_number" binding:"required,min=10,max=10"`
+}
+
+// PhoneChangeVerifyRequest represents the request for completing a phone number change.
+// Both the current and the new number must confirm the change.
+type PhoneChangeVerifyRequest struct {
+	NewPhoneNumber string `json:"new_phone_number" binding:"required,min=10,max=10"`
+	OldCode        string `json:"old_code" binding:"required,len=6,numeric"`
+	NewCode        string `json:"new_code" binding:"required,len=6,numeric"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+// Outbox event types
+const (
+	OutboxEventRTODrop    = "rto_drop"
+	OutboxEventRTORestore = "rto_restore"
+
+	// OutboxEventPaymentRefund returns the money of a completed payment
+	// whose order was cancelled or returned
+	OutboxEventPaymentRefund = "payment_refund"
+)
+
+// Outbox event statuses
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusFailed    = "failed"
+
+	// OutboxStatusSkipped marks events that must not be delivered any more,
+	// e.g. the drop and restore of a cancelled unit whose drop failed
+	OutboxStatusSkipped = "skipped"
+)
+
+// OutboxEvent represents the outbox_events table structure.
+// Events are written in the same transaction as the order they belong to
+// and delivered afterwards by the outbox dispatcher.
+type OutboxEvent struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	OrderID       string     `json:"order_id" gorm:"column:order_id;type:varchar(50);index"`
+	EventType     string     `json:"event_type" gorm:"column:event_type;type:varchar(50);not null"`
+	Payload       string     `json:"payload" gorm:"column:payload;type:text"`
+	Status        string     `json:"status" gorm:"column:status;type:varchar(20);index:idx_outbox_status_next;not null"`
+	Attempts      int        `json:"attempts" gorm:"column:attempts;default:0"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"column:next_attempt_at;index:idx_outbox_status_next"`
+	LastError     string     `json:"last_error,omitempty" gorm:"column:last_error;type:text"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty" gorm:"column:delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for OutboxEvent
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// Payment methods accepted at checkout
+const (
+	PaymentMethodCOD    = "cod"
+	PaymentMethodOnline = "online"
+)
+
+// Payment statuses
+const (
+	PaymentStatusPending   = "pending"
+	PaymentStatusSucceeded = "succeeded"
+	PaymentStatusFailed    = "failed"
+	PaymentStatusRefunded  = "refunded"
+)
+
+// Payment represents the payments table structure
+type Payment struct {
+	ID            uint      `json:"-" gorm:"primaryKey"`
+	PaymentID     string    `json:"payment_id" gorm:"column:payment_id;type:varchar(50);uniqueIndex:idx_payment_id;not null"`
+	OrderID       string    `json:"order_id" gorm:"column:order_id;type:varchar(50);index;not null"`
+	UserID        string    `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	Method        string    `json:"method" gorm:"column:method;type:varchar(20);not null"`
+	Provider      string    `json:"provider" gorm:"column:provider;type:varchar(50);not null"`
+	ProviderRef   string    `json:"provider_ref" gorm:"column:provider_ref;type:varchar(100);index"`
+	Amount        float64   `json:"amount" gorm:"column:amount;type:decimal(10,2)"`
+	Currency      string    `json:"currency" gorm:"column:currency;type:varchar(3);default:'INR'"`
+	Status        string    `json:"status" gorm:"column:status;type:varchar(20);not null"`
+	NextAction    string    `json:"next_action,omitempty" gorm:"column:next_action;type:varchar(255)"`
+	FailureReason string    `json:"failure_reason,omitempty" gorm:"column:failure_reason;type:varchar(255)"`
+	RefundRef     string    `json:"refund_ref,omitempty" gorm:"column:refund_ref;type:varchar(100)"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Payment
+func (Payment) TableName() string {
+	return "payments"
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Reservation statuses
+const (
+	ReservationStatusHeld     = "held"
+	ReservationStatusSold     = "sold"
+	ReservationStatusReleased = "released"
+	ReservationStatusExpired  = "expired"
+
+	// ReservationStatusReturned claims an expired unit that is being sent
+	// back to its supplier, so it can no longer be sold
+	ReservationStatusReturned = "returned"
+)
+
+// InventoryReservation represents the inventory_reservations table structure.
+// An RTO unit is identified by its code, product ID and sub order number, so
+// several units of one product can be sold separately. ActiveUnit is set to
+// "<code>:<product_id>:<sub_order_num>" while the unit is held, sold or
+// returned and cleared otherwise, so the unique index allows at most one live
+// reservation per unit.
+type InventoryReservation struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ReservationID string    `json:"reservation_id" gorm:"column:reservation_id;type:varchar(50);uniqueIndex:idx_reservation_id;not null"`
+	UserID        string    `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	Code          string    `json:"code" gorm:"column:code;type:varchar(20);not null"`
+	ProductID     string    `json:"product_id" gorm:"column:product_id;type:varchar(50);not null"`
+	SubOrderNum   string    `json:"sub_order_num,omitempty" gorm:"column:sub_order_num;type:varchar(50)"`
+	CatalogID     string    `json:"catalog_id" gorm:"column:catalog_id;type:varchar(50)"`
+	Status        string    `json:"status" gorm:"column:status;type:varchar(20);index:idx_reservation_status_expiry;not null"`
+	ActiveUnit    *string   `json:"-" gorm:"column:active_unit;type:varchar(150);uniqueIndex:idx_reservation_active_unit"`
+	OrderID       string    `json:"order_id,omitempty" gorm:"column:order_id;type:varchar(50);index"`
+	ExpiresAt     time.Time `json:"expires_at" gorm:"column:expires_at;index:idx_reservation_status_expiry"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for InventoryReservation
+func (InventoryReservation) TableName() string {
+	return "inventory_reservations"
+}
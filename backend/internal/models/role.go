@@ -0,0 +1,56 @@
+package models
+
+// User roles
+const (
+	RoleCustomer = "customer"
+	RoleOps      = "ops"
+	RoleAdmin    = "admin"
+)
+
+// Permissions granted to roles
+const (
+	PermissionRTOManage     = "rto:manage"
+	PermissionWidgetsManage = "widgets:manage"
+	PermissionOrdersManage  = "orders:manage"
+	PermissionOutboxManage  = "outbox:manage"
+	PermissionUsersManage   = "users:manage"
+)
+
+// rolePermissions lists the permissions of each role. Customers have none;
+// everything they can do is limited to their own account.
+var rolePermissions = map[string][]string{
+	RoleCustomer: {},
+	RoleOps: {
+		PermissionRTOManage, PermissionWidgetsManage, PermissionOrdersManage, PermissionOutboxManage,
+	},
+	RoleAdmin: {
+		PermissionRTOManage, PermissionWidgetsManage, PermissionOrdersManage, PermissionOutboxManage,
+		PermissionUsersManage,
+	},
+}
+
+// IsValidRole reports whether role is a known role
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission reports whether a role grants the permission
+func RoleHasPermission(role, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePermissions returns the permissions granted to a role
+func RolePermissions(role string) []string {
+	return append([]string(nil), rolePermissions[role]...)
+}
+
+// UpdateRoleRequest represents the request for changing a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=customer ops admin"`
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// RTOAgePolicyDefault is the category of the policy applied to categories
+// without a policy of their own
+const RTOAgePolicyDefault = "*"
+
+// SupplierReturnPending is the status of a supplier return waiting to be picked up
+const SupplierReturnPending = "pending"
+
+// MarkdownStep takes an extra percentage off the price once an RTO unit
+// has been waiting for AfterDays days
+type MarkdownStep struct {
+	AfterDays int `json:"after_days" binding:"min=1"`
+	Percent   int `json:"percent" binding:"min=1,max=90"`
+}
+
+// MarkdownSteps is a custom type to handle JSON serialization/deserialization
+type MarkdownSteps []MarkdownStep
+
+// Value implements the driver.Valuer interface
+func (m MarkdownSteps) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan implements the sql.Scanner interface
+func (m *MarkdownSteps) Scan(value interface{}) error {
+	if value == nil {
+		*m = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New("cannot scan non-string/non-byte value into MarkdownSteps")
+	}
+
+	return json.Unmarshal(bytes, m)
+}
+
+// RTOAgePolicy controls how long RTO units of a category stay listed and how
+// their price drops while they wait. The policy with category "*" applies to
+// every category without its own.
+type RTOAgePolicy struct {
+	ID            uint          `json:"id" gorm:"primaryKey"`
+	Category      string        `json:"category" gorm:"column:category;type:varchar(100);uniqueIndex;not null"`
+	HideAfterDays int           `json:"hide_after_days" gorm:"column:hide_after_days;not null"`
+	MarkdownSteps MarkdownSteps `json:"markdown_steps" gorm:"column:markdown_steps;type:json"`
+	UpdatedBy     string        `json:"updated_by" gorm:"column:updated_by;type:varchar(100)"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// TableName specifies the table name for RTOAgePolicy
+func (RTOAgePolicy) TableName() string {
+	return "rto_age_policies"
+}
+
+// MarkdownPercent returns the markdown that applies at the given age
+func (p RTOAgePolicy) MarkdownPercent(ageDays int) int {
+	percent := 0
+	for _, step := range p.MarkdownSteps {
+		if ageDays >= step.AfterDays && step.Percent > percent {
+			percent = step.Percent
+		}
+	}
+	return percent
+}
+
+// UpdateRTOAgePolicyRequest represents the request for setting the age policy of a category
+type UpdateRTOAgePolicyRequest struct {
+	HideAfterDays int            `json:"hide_after_days" binding:"required,min=1,max=365"`
+	MarkdownSteps []MarkdownStep `json:"markdown_steps" binding:"dive"`
+}
+
+// SupplierReturn represents an expired RTO unit queued to go back to its supplier
+type SupplierReturn struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UnitKey     string    `json:"unit_key" gorm:"column:unit_key;type:varchar(150);uniqueIndex;not null"`
+	Code        string    `json:"code" gorm:"column:code;type:varchar(20);index;not null"`
+	ProductID   int64     `json:"product_id" gorm:"column:product_id;not null"`
+	CatalogID   int64     `json:"catalog_id" gorm:"column:catalog_id;not null"`
+	SubOrderNum string    `json:"sub_order_num" gorm:"column:sub_order_num;type:varchar(50)"`
+	SupplierID  string    `json:"supplier_id" gorm:"column:supplier_id;type:varchar(50);index"`
+	Category    string    `json:"category" gorm:"column:category;type:varchar(100)"`
+	OrderDate   string    `json:"order_date" gorm:"column:order_date;type:varchar(10)"`
+	AgeDays     int       `json:"age_days" gorm:"column:age_days"`
+	Status      string    `json:"status" gorm:"column:status;type:varchar(20);index;not null;default:'pending'"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for SupplierReturn
+func (SupplierReturn) TableName() string {
+	return "supplier_return_queue"
+}
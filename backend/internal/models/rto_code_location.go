@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// RTOCodeLocation places an RTO code in a city and state, and optionally on
+// the map, so that nearby codes can serve users whose own code has no units
+type RTOCodeLocation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Code      string    `json:"code" gorm:"column:code;type:varchar(20);uniqueIndex;not null"`
+	City      string    `json:"city" gorm:"column:city;type:varchar(100);index"`
+	State     string    `json:"state" gorm:"column:state;type:varchar(100);index"`
+	Latitude  *float64  `json:"latitude,omitempty" gorm:"column:latitude"`
+	Longitude *float64  `json:"longitude,omitempty" gorm:"column:longitude"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for RTOCodeLocation
+func (RTOCodeLocation) TableName() string {
+	return "rto_code_locations"
+}
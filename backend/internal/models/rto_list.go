@@ -14,6 +14,10 @@ type RTOProduct struct {
 	OrderDate   string `json:"order_date"`
 	ProductID   int64  `json:"product_id"`
 	SubOrderNum string `json:"sub_order_num"`
+
+	// Code is the RTO code holding the unit. It is filled in when units are
+	// read for a code and is not stored in the list itself.
+	Code string `json:"-"`
 }
 
 // RTOProducts is a custom type to handle JSON serialization/deserialization
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// Session represents the sessions table structure.
+// A session is one login on one device; its ID is the token family ID
+// carried by every access and refresh token issued for that login.
+type Session struct {
+	ID         uint       `json:"-" gorm:"primaryKey"`
+	SessionID  string     `json:"session_id" gorm:"column:session_id;type:varchar(50);uniqueIndex:idx_session_id;not null"`
+	UserID     string     `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	DeviceInfo string     `json:"device_info" gorm:"column:device_info;type:varchar(255)"`
+	IPAddress  string     `json:"ip_address" gorm:"column:ip_address;type:varchar(45)"`
+	LastSeenAt time.Time  `json:"last_seen_at" gorm:"column:last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"column:revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	// Current is set when listing sessions to mark the caller's own session
+	Current bool `json:"current" gorm:"-"`
+}
+
+// TableName specifies the table name for Session
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// SessionInfo describes the client a session is created for
+type SessionInfo struct {
+	DeviceInfo string
+	IPAddress  string
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+)
+
+// Trending catalog scopes
+const (
+	TrendingScopeGlobal   = "global"
+	TrendingScopeCity     = "city"
+	TrendingScopeCategory = "category"
+)
+
+// Catalog override actions
+const (
+	CatalogOverridePin   = "pin"
+	CatalogOverrideBlock = "block"
+)
+
+// CatalogClickEvent records a user opening a catalog
+type CatalogClickEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"column:user_id;type:varchar(50);index"`
+	CatalogID string    `json:"catalog_id" gorm:"column:catalog_id;type:varchar(50);not null"`
+	City      string    `json:"city" gorm:"column:city;type:varchar(100)"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName specifies the table name for CatalogClickEvent
+func (CatalogClickEvent) TableName() string {
+	return "catalog_click_events"
+}
+
+// CatalogClickRequest represents the request for recording a catalog click
+type CatalogClickRequest struct {
+	CatalogID string `json:"catalog_id" binding:"required,max=50"`
+}
+
+// TrendingCatalog is one entry of a computed trending list. Lists are kept
+// per city and per category, plus one global list with an empty scope value.
+type TrendingCatalog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Scope      string    `json:"scope" gorm:"column:scope;type:varchar(20);uniqueIndex:idx_trending_scope_catalog;not null"`
+	ScopeValue string    `json:"scope_value" gorm:"column:scope_value;type:varchar(100);uniqueIndex:idx_trending_scope_catalog;not null"`
+	CatalogID  string    `json:"catalog_id" gorm:"column:catalog_id;type:varchar(50);uniqueIndex:idx_trending_scope_catalog;not null"`
+	Position   int       `json:"position" gorm:"column:position;not null"`
+	Score      float64   `json:"score" gorm:"column:score"`
+	ComputedAt time.Time `json:"computed_at" gorm:"column:computed_at"`
+}
+
+// TableName specifies the table name for TrendingCatalog
+func (TrendingCatalog) TableName() string {
+	return "trending_catalogs"
+}
+
+// CatalogOverride pins a catalog to the top of every trending list or
+// keeps it out of them
+type CatalogOverride struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CatalogID string    `json:"catalog_id" gorm:"column:catalog_id;type:varchar(50);uniqueIndex;not null"`
+	Action    string    `json:"action" gorm:"column:action;type:varchar(10);not null"`
+	Reason    string    `json:"reason" gorm:"column:reason;type:varchar(255)"`
+	UpdatedBy string    `json:"updated_by" gorm:"column:updated_by;type:varchar(100)"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for CatalogOverride
+func (CatalogOverride) TableName() string {
+	return "catalog_overrides"
+}
+
+// CatalogOverrideRequest represents the request for pinning or blocklisting a catalog
+type CatalogOverrideRequest struct {
+	Action string `json:"action" binding:"required,oneof=pin block"`
+	Reason string `json:"reason" binding:"max=255"`
+}
@@ -1,20 +1,32 @@
 package models
 
 import (
+	"encoding/json"
+	"meesho-clone/internal/pii"
 	"time"
 
 	"gorm.io/gorm"
 )
 
-// User represents a user in the system
+// User represents a user in the system.
+// The phone number is encrypted at rest and looked up through PhoneHash, a
+// blind index that is NULL only for rows not yet migrated by cmd/encrypt-phones.
 type User struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
 	UserID      string         `json:"user_id" gorm:"uniqueIndex:idx_user_id,length:255;not null"`
-	PhoneNumber string         `json:"phone_number" gorm:"uniqueIndex:idx_phone_number,length:20;not null"`
+	PhoneNumber string         `json:"phone_number" gorm:"type:varchar(255);serializer:encrypted;not null"`
+	PhoneHash   *string        `json:"-" gorm:"column:phone_hash;type:varchar(64);uniqueIndex:idx_phone_hash"`
 	Name        string         `json:"name"`
+	Email       string         `json:"email" gorm:"type:varchar(255)"`
+	Language    string         `json:"language" gorm:"type:varchar(10)"`
+	Gender      string         `json:"gender" gorm:"type:varchar(20)"`
+	Role        string         `json:"role" gorm:"type:varchar(20);not null;default:'customer'"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// AnonymisedAt is set once a deleted account's personal data has been scrubbed
+	AnonymisedAt *time.Time `json:"-" gorm:"column:anonymised_at"`
 }
 
 // TableName returns the table name for User model
@@ -22,22 +34,87 @@ func (User) TableName() string {
 	return "users"
 }
 
-// LoginRequest represents the login request payload
-type LoginRequest struct {
-	PhoneNumber string `json:"phone_number" binding:"required,min=10,max=10"`
+// MarshalJSON masks the phone number in every API response
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	masked := user(u)
+	masked.PhoneNumber = pii.MaskPhone(u.PhoneNumber)
+	return json.Marshal(masked)
 }
 
-// LoginResponse represents the login response
-type LoginResponse struct {
-	UserID      string `json:"user_id"`
-	PhoneNumber string `json:"phone_number"`
-	Name        string `json:"name"`
-	Message     string `json:"message"`
+// PhoneHashOf returns the blind index value for a phone number
+func PhoneHashOf(phoneNumber string) *string {
+	hash := pii.BlindIndex(phoneNumber)
+	return &hash
+}
+
+// UpdateProfileRequest represents the editable profile fields.
+// Fields left out of the request are not changed.
+type UpdateProfileRequest struct {
+	Name     *string `json:"name" binding:"omitnil,min=2,max=100"`
+	Email    *string `json:"email" binding:"omitnil,email,max=255"`
+	Language *string `json:"language" binding:"omitnil,oneof=en hi bn mr te ta gu kn ml or pa"`
+	Gender   *string `json:"gender" binding:"omitnil,oneof=male female other prefer_not_to_say"`
 }
 
-// HomescreenRequest represents request for homescreen data
-type HomescreenRequest struct {
-	UserID string `json:"user_id" binding:"required"`
+// ProfileAudit represents the profile_audit_log table structure.
+// Each row records one field of a profile update.
+type ProfileAudit struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    string    `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	Field     string    `json:"field" gorm:"column:field;type:varchar(30);not null"`
+	OldValue  string    `json:"old_value" gorm:"column:old_value;type:varchar(255)"`
+	NewValue  string    `json:"new_value" gorm:"column:new_value;type:varchar(255)"`
+	Actor     string    `json:"actor" gorm:"column:actor;type:varchar(100)"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for ProfileAudit
+func (ProfileAudit) TableName() string {
+	return "profile_audit_log"
+}
+
+// PhoneNumberHistory represents the phone_number_history table structure.
+// A row is kept for every number a user has moved away from.
+type PhoneNumberHistory struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      string    `json:"user_id" gorm:"column:user_id;type:varchar(50);index;not null"`
+	PhoneNumber string    `json:"phone_number" gorm:"column:phone_number;type:varchar(255);serializer:encrypted;not null"`
+	ReplacedBy  string    `json:"replaced_by" gorm:"column:replaced_by;type:varchar(255);serializer:encrypted;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for PhoneNumberHistory
+func (PhoneNumberHistory) TableName() string {
+	return "phone_number_history"
+}
+
+// UserLookupAudit represents the user_lookup_audit table structure.
+// Each row records a call to the validate endpoint; the phone number is only
+// kept as its blind index.
+type UserLookupAudit struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	CallerUserID  string    `json:"caller_user_id" gorm:"column:caller_user_id;type:varchar(50);index"`
+	QueryUserID   string    `json:"query_user_id" gorm:"column:query_user_id;type:varchar(50)"`
+	PhoneHash     string    `json:"-" gorm:"column:phone_hash;type:varchar(64);index"`
+	IPAddress     string    `json:"ip_address" gorm:"column:ip_address;type:varchar(45);index"`
+	Authenticated bool      `json:"authenticated" gorm:"column:authenticated;not null"`
+	Outcome       string    `json:"outcome" gorm:"column:outcome;type:varchar(30);not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName specifies the table name for UserLookupAudit
+func (UserLookupAudit) TableName() string {
+	return "user_lookup_audit"
+}
+
+// LoginResponse represents the login response
+type LoginResponse struct {
+	UserID      string     `json:"user_id"`
+	PhoneNumber string     `json:"phone_number"`
+	Name        string     `json:"name"`
+	Message     string     `json:"message"`
+	Tokens      *TokenPair `json:"tokens,omitempty"`
 }
 
 // MeeshoAPIResponse represents the response from Meesho API
@@ -0,0 +1,159 @@
+// Package pii encrypts personal data stored in the database and derives
+// blind indexes so that encrypted values can still be looked up.
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// encryptedPrefix marks a value encrypted with the current scheme. Values
+// without it are legacy plaintext written before encryption was enabled.
+const encryptedPrefix = "v1:"
+
+var (
+	mu       sync.RWMutex
+	aead     cipher.AEAD
+	indexKey []byte
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// Configure sets the AES-256 key used for encryption and the HMAC key used
+// for blind indexes. Both keys must be 32 bytes.
+func Configure(encryptionKey, blindIndexKey []byte) error {
+	if len(encryptionKey) != 32 {
+		return fmt.Errorf("encryption key must be 32 bytes, got %d", len(encryptionKey))
+	}
+	if len(blindIndexKey) != 32 {
+		return fmt.Errorf("blind index key must be 32 bytes, got %d", len(blindIndexKey))
+	}
+
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	aead = gcm
+	indexKey = append([]byte(nil), blindIndexKey...)
+	return nil
+}
+
+// Encrypt seals a value with AES-GCM under a random nonce
+func Encrypt(plaintext string) (string, error) {
+	mu.RLock()
+	gcm := aead
+	mu.RUnlock()
+	if gcm == nil {
+		return "", errors.New("pii: encryption key not configured")
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Legacy plaintext is returned unchanged.
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	mu.RLock()
+	gcm := aead
+	mu.RUnlock()
+	if gcm == nil {
+		return "", errors.New("pii: encryption key not configured")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", errors.New("pii: malformed ciphertext")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("pii: ciphertext could not be decrypted")
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether a stored value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// BlindIndex returns a keyed hash of a value for equality lookups
+func BlindIndex(value string) string {
+	mu.RLock()
+	key := indexKey
+	mu.RUnlock()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// MaskPhone hides all but the last four digits of a phone number
+func MaskPhone(phone string) string {
+	if len(phone) <= 4 {
+		return strings.Repeat("*", len(phone))
+	}
+	return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
+}
+
+// EncryptedSerializer stores string fields tagged `serializer:encrypted` encrypted
+type EncryptedSerializer struct{}
+
+// Scan implements the schema.SerializerInterface interface
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+		return nil
+	case []byte:
+		stored = string(v)
+	case string:
+		stored = v
+	default:
+		return fmt.Errorf("pii: unsupported type %T for encrypted field", dbValue)
+	}
+
+	plaintext, err := Decrypt(stored)
+	if err != nil {
+		return err
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+// Value implements the schema.SerializerValuerInterface interface
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("pii: unsupported type %T for encrypted field", fieldValue)
+	}
+	return Encrypt(value)
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AccountService handles account deletion and personal data export
+type AccountService struct {
+	db            *gorm.DB
+	gracePeriod   time.Duration
+	sweepInterval time.Duration
+}
+
+// NewAccountService creates a new account service
+func NewAccountService() *AccountService {
+	return &AccountService{
+		db:            configs.DB,
+		gracePeriod:   getEnvDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+		sweepInterval: getEnvDuration("ACCOUNT_ANONYMISE_INTERVAL", time.Hour),
+	}
+}
+
+// DeleteAccount soft-deletes the user and logs out every session. Personal
+// data, including the user's RTO mapping, is scrubbed later by
+// AnonymiseDeleted once the grace period has passed; logging in again before
+// then restores the account as it was.
+func (s *AccountService) DeleteAccount(userID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&user).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("user not found")
+			}
+			return fmt.Errorf("database error: %v", err)
+		}
+
+		if err := NewSessionService().revokeAllSessions(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Delete(&user).Error; err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+}
+
+// AnonymiseDeleted scrubs the personal data of accounts deleted more than the
+// grace period ago and returns how many were anonymised. Orders and invoices
+// are kept for accounting but no longer lead back to a phone number or name.
+func (s *AccountService) AnonymiseDeleted() (int, error) {
+	var users []models.User
+	err := s.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND anonymised_at IS NULL", time.Now().Add(-s.gracePeriod)).
+		Limit(100).
+		Find(&users).Error
+	if err != nil {
+		return 0, fmt.Errorf("database error: %v", err)
+	}
+
+	anonymised := 0
+	for _, user := range users {
+		if err := s.anonymise(user); err != nil {
+			fmt.Printf("Warning: Failed to anonymise user %s: %v\n", user.UserID, err)
+			continue
+		}
+		anonymised++
+	}
+	return anonymised, nil
+}
+
+// anonymise replaces the user's phone number and name, scrubs the delivery
+// and billing details of their orders and invoices and removes the rows that
+// still hold personal data
+func (s *AccountService) anonymise(user models.User) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// OTPs are keyed by phone number, including numbers the user moved away from
+		var history []models.PhoneNumberHistory
+		if err := tx.Where("user_id = ?", user.UserID).Find(&history).Error; err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+		phoneHashes := []string{*models.PhoneHashOf(user.PhoneNumber)}
+		for _, entry := range history {
+			phoneHashes = append(phoneHashes, *models.PhoneHashOf(entry.PhoneNumber), *models.PhoneHashOf(entry.ReplacedBy))
+		}
+		if err := tx.Where("phone_hash IN ?", phoneHashes).Delete(&models.OTPChallenge{}).Error; err != nil {
+			return fmt.Errorf("failed to remove OTPs: %w", err)
+		}
+
+		// Clearing the blind index frees the number for a new account
+		err := tx.Unscoped().Model(&models.User{}).Where("user_id = ?", user.UserID).Updates(map[string]interface{}{
+			"phone_number":  "deleted_" + strings.TrimPrefix(user.UserID, "user_"),
+			"phone_hash":    nil,
+			"name":          "Deleted user",
+			"email":         "",
+			"language":      "",
+			"gender":        "",
+			"anonymised_at": time.Now(),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to anonymise user: %w", err)
+		}
+
+		// The amounts stay for accounting; only who and where is removed
+		err = tx.Model(&models.Order{}).Where("user_id = ?", user.UserID).Updates(map[string]interface{}{
+			"shipping_name":    "Deleted user",
+			"shipping_phone":   "",
+			"shipping_address": "",
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to anonymise orders: %w", err)
+		}
+		err = tx.Model(&models.Invoice{}).Where("user_id = ?", user.UserID).Updates(map[string]interface{}{
+			"billing_name":    "Deleted user",
+			"billing_address": "",
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to anonymise invoices: %w", err)
+		}
+
+		for _, model := range []interface{}{&models.Address{}, &models.PhoneNumberHistory{}, &models.ProfileAudit{}, &models.Session{}, &models.UserMapping{}} {
+			if err := tx.Unscoped().Where("user_id = ?", user.UserID).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to remove personal data: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// StartAnonymiser periodically anonymises deleted accounts until the context is cancelled
+func (s *AccountService) StartAnonymiser(ctx context.Context) {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			anonymised, err := s.AnonymiseDeleted()
+			if err != nil {
+				fmt.Printf("Warning: Account anonymisation failed: %v\n", err)
+			} else if anonymised > 0 {
+				fmt.Printf("Anonymised %d deleted accounts\n", anonymised)
+			}
+		}
+	}
+}
+
+// exportedProfile is a User without the phone masking applied to API
+// responses, so that the export carries the user's full number
+type exportedProfile models.User
+
+// ExportUserData returns a zip archive with the user's profile, orders,
+// addresses and sessions, each as a JSON file
+func (s *AccountService) ExportUserData(userID string) ([]byte, error) {
+	var user models.User
+	if err := s.db.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	var orders []models.Order
+	if err := s.db.Preload("Items").Where("user_id = ?", userID).Order("created_at DESC").Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	var addresses []models.Address
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&addresses).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	var sessions []models.Session
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", exportedProfile(user)},
+		{"orders.json", orders},
+		{"addresses.json", addresses},
+		{"sessions.json", sessions},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		content, err := json.MarshalIndent(file.data, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", file.name, err)
+		}
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", file.name, err)
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish export: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAddressNotFound is returned when the address does not exist for the user
+	ErrAddressNotFound = errors.New("address not found")
+
+	// ErrAddressNotServiceable is returned when the pincode cannot be served from the RTO code
+	ErrAddressNotServiceable = errors.New("address pincode is not serviceable from this RTO location")
+
+	// ErrInvalidServiceableRule is returned for a rule that is not an RTO code
+	// with a pincode or pincode prefix of 1 to 6 digits
+	ErrInvalidServiceableRule = errors.New("invalid serviceability rule")
+)
+
+// AddressService handles delivery address operations
+type AddressService struct {
+	db *gorm.DB
+}
+
+// NewAddressService creates a new address service
+func NewAddressService() *AddressService {
+	return &AddressService{
+		db: configs.DB,
+	}
+}
+
+// generateAddressID generates a unique address ID
+func (s *AddressService) generateAddressID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return "addr_" + hex.EncodeToString(bytes)
+}
+
+// ListAddresses returns the user's addresses, default first
+func (s *AddressService) ListAddresses(userID string) ([]models.Address, error) {
+	var addresses []models.Address
+	err := s.db.Where("user_id = ?", userID).Order("is_default DESC, created_at DESC").Find(&addresses).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return addresses, nil
+}
+
+// GetAddress returns one of the user's addresses
+func (s *AddressService) GetAddress(userID, addressID string) (*models.Address, error) {
+	var address models.Address
+	err := s.db.Where("user_id = ? AND address_id = ?", userID, addressID).First(&address).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrAddressNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return &address, nil
+}
+
+// CreateAddress adds an address; the user's first address becomes the default
+func (s *AddressService) CreateAddress(userID string, req models.AddressRequest) (*models.Address, error) {
+	address := models.Address{
+		AddressID: s.generateAddressID(),
+		UserID:    userID,
+	}
+	applyAddressRequest(&address, req)
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.Address{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+		if count == 0 {
+			address.IsDefault = true
+		}
+
+		if address.IsDefault {
+			if err := s.clearDefault(tx, userID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(&address).Error; err != nil {
+			return fmt.Errorf("failed to create address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// UpdateAddress replaces the fields of an existing address
+func (s *AddressService) UpdateAddress(userID, addressID string, req models.AddressRequest) (*models.Address, error) {
+	address, err := s.GetAddress(userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+
+	wasDefault := address.IsDefault
+	applyAddressRequest(address, req)
+	// The default can be moved to another address but not simply switched off
+	address.IsDefault = wasDefault || req.IsDefault
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if address.IsDefault && !wasDefault {
+			if err := s.clearDefault(tx, userID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(address).Error; err != nil {
+			return fmt.Errorf("failed to update address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// SetDefaultAddress makes the address the user's default
+func (s *AddressService) SetDefaultAddress(userID, addressID string) (*models.Address, error) {
+	address, err := s.GetAddress(userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.clearDefault(tx, userID); err != nil {
+			return err
+		}
+		return tx.Model(address).Update("is_default", true).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set default address: %w", err)
+	}
+	address.IsDefault = true
+	return address, nil
+}
+
+// DeleteAddress removes an address and promotes the newest remaining one if it was the default
+func (s *AddressService) DeleteAddress(userID, addressID string) error {
+	address, err := s.GetAddress(userID, addressID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(address).Error; err != nil {
+			return fmt.Errorf("failed to delete address: %w", err)
+		}
+		if !address.IsDefault {
+			return nil
+		}
+
+		var next models.Address
+		err := tx.Where("user_id = ?", userID).Order("created_at DESC").First(&next).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+}
+
+// ResolveDeliveryAddress loads the address an order should ship to and checks
+// that its pincode can be served from the RTO code holding the items
+func (s *AddressService) ResolveDeliveryAddress(userID, addressID, code string) (*models.Address, error) {
+	address, err := s.GetAddress(userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.CheckServiceable(code, address); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// CheckServiceable returns ErrAddressNotServiceable when the address cannot
+// be served from the RTO code, or when there is no code to ship from
+func (s *AddressService) CheckServiceable(code string, address *models.Address) error {
+	if code == "" {
+		return fmt.Errorf("%w: %s has no RTO location to ship from", ErrAddressNotServiceable, address.Pincode)
+	}
+
+	serviceable, err := s.IsServiceable(code, address.Pincode)
+	if err != nil {
+		return err
+	}
+	if !serviceable {
+		return fmt.Errorf("%w: %s from %s", ErrAddressNotServiceable, address.Pincode, code)
+	}
+	return nil
+}
+
+// IsServiceable reports whether an RTO code can deliver to a pincode.
+// Codes without any serviceability rules serve no pincode.
+func (s *AddressService) IsServiceable(code, pincode string) (bool, error) {
+	if code == "" || pincode == "" {
+		return false, nil
+	}
+
+	// Match the full pincode or any of its prefixes
+	prefixes := make([]string, 0, len(pincode))
+	for i := 1; i <= len(pincode); i++ {
+		prefixes = append(prefixes, pincode[:i])
+	}
+
+	var matches int64
+	err := s.db.Model(&models.ServiceablePincode{}).
+		Where("code = ? AND pincode IN ?", code, prefixes).
+		Count(&matches).Error
+	if err != nil {
+		return false, fmt.Errorf("database error: %v", err)
+	}
+	return matches > 0, nil
+}
+
+// ListServiceableRules returns the serviceability rules of an RTO code, or of
+// every code when code is empty
+func (s *AddressService) ListServiceableRules(code string) ([]models.ServiceablePincode, error) {
+	query := s.db.Order("code, pincode")
+	if code != "" {
+		query = query.Where("code = ?", code)
+	}
+
+	var rules []models.ServiceablePincode
+	if err := query.Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return rules, nil
+}
+
+// SetServiceablePincodes replaces the pincodes and pincode prefixes an RTO
+// code delivers to. An empty list leaves the code serving no pincode.
+func (s *AddressService) SetServiceablePincodes(code string, pincodes []string) ([]models.ServiceablePincode, error) {
+	if code == "" || len(code) > 20 {
+		return nil, fmt.Errorf("%w: code must be 1 to 20 characters", ErrInvalidServiceableRule)
+	}
+
+	rules := make([]models.ServiceablePincode, 0, len(pincodes))
+	seen := make(map[string]bool)
+	for _, pincode := range pincodes {
+		if !isPincodePrefix(pincode) {
+			return nil, fmt.Errorf("%w: %q is not a pincode or pincode prefix", ErrInvalidServiceableRule, pincode)
+		}
+		if seen[pincode] {
+			continue
+		}
+		seen[pincode] = true
+		rules = append(rules, models.ServiceablePincode{Code: code, Pincode: pincode})
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("code = ?", code).Delete(&models.ServiceablePincode{}).Error; err != nil {
+			return fmt.Errorf("failed to clear serviceability rules: %w", err)
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		if err := tx.Create(&rules).Error; err != nil {
+			return fmt.Errorf("failed to store serviceability rules: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ListServiceableRules(code)
+}
+
+// isPincodePrefix reports whether value is 1 to 6 digits
+func isPincodePrefix(value string) bool {
+	if len(value) == 0 || len(value) > 6 {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// clearDefault unsets the default flag on all of the user's addresses
+func (s *AddressService) clearDefault(tx *gorm.DB, userID string) error {
+	err := tx.Model(&models.Address{}).Where("user_id = ? AND is_default = ?", userID, true).Update("is_default", false).Error
+	if err != nil {
+		return fmt.Errorf("failed to clear default address: %w", err)
+	}
+	return nil
+}
+
+// applyAddressRequest copies the editable fields of a request onto an address
+func applyAddressRequest(address *models.Address, req models.AddressRequest) {
+	address.Name = req.Name
+	address.Phone = req.Phone
+	address.Line1 = req.Line1
+	address.Line2 = req.Line2
+	address.Landmark = req.Landmark
+	address.City = req.City
+	address.State = req.State
+	address.Pincode = req.Pincode
+	address.IsDefault = req.IsDefault
+}
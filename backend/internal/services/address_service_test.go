@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetServiceablePincodesReplacesTheRulesOfACode(t *testing.T) {
+	openTestDB(t)
+	service := NewAddressService()
+
+	if _, err := service.SetServiceablePincodes("C1", []string{"560", "110001"}); err != nil {
+		t.Fatalf("SetServiceablePincodes: %v", err)
+	}
+	if ok, _ := service.IsServiceable("C1", "560034"); !ok {
+		t.Fatal("560034 should be served through the 560 prefix")
+	}
+
+	rules, err := service.SetServiceablePincodes("C1", []string{"110001", "110001"})
+	if err != nil {
+		t.Fatalf("SetServiceablePincodes: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("expected 1 rule after replacing, got %d", len(rules))
+	}
+	if ok, _ := service.IsServiceable("C1", "560034"); ok {
+		t.Fatal("560034 is still served after its prefix was removed")
+	}
+}
+
+func TestSetServiceablePincodesRejectsNonDigitPrefixes(t *testing.T) {
+	_, err := (&AddressService{}).SetServiceablePincodes("C1", []string{"56A"})
+	if !errors.Is(err, ErrInvalidServiceableRule) {
+		t.Fatalf("expected ErrInvalidServiceableRule, got %v", err)
+	}
+}
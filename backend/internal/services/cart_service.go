@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCartItemNotFound is returned when the product is not in the user's cart
+	ErrCartItemNotFound = errors.New("cart item not found")
+
+	// ErrCartEmpty is returned when checking out a cart with nothing orderable in it
+	ErrCartEmpty = errors.New("cart has no items that can be ordered")
+
+	// ErrProductNotFound is returned when a product has no price_product_info row
+	ErrProductNotFound = errors.New("product not found")
+)
+
+// Reasons reported for cart items dropped at checkout
+const (
+	CheckoutReasonSold           = "already_sold"
+	CheckoutReasonUnavailable    = "product_unavailable"
+	CheckoutReasonNotServiceable = "not_serviceable"
+)
+
+// CartService handles cart-related operations
+type CartService struct {
+	db             *gorm.DB
+	orderService   *OrderService
+	addressService *AddressService
+}
+
+// NewCartService creates a new cart service
+func NewCartService() *CartService {
+	return &CartService{
+		db:             configs.DB,
+		orderService:   NewOrderService(),
+		addressService: NewAddressService(),
+	}
+}
+
+// GetCart returns the user's cart with its totals
+func (s *CartService) GetCart(userID string) (*models.CartSummary, error) {
+	var items []models.CartItem
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	summary := &models.CartSummary{
+		UserID: userID,
+		Items:  items,
+	}
+	for _, item := range items {
+		summary.TotalItems += item.Quantity
+		summary.Subtotal += item.UnitPrice * float64(item.Quantity)
+	}
+	return summary, nil
+}
+
+// AddItem adds a product listed at an RTO code to the cart, increasing the
+// quantity if it is already there. The item is priced like the unit the
+// catalog shows, and products with no unit left to buy are rejected.
+func (s *CartService) AddItem(userID, productID, catalogID, rtoCode string, quantity int) (*models.CartItem, error) {
+	if rtoCode == "" {
+		return nil, fmt.Errorf("product %s: %w", productID, ErrNoRTOCode)
+	}
+
+	units, err := s.orderService.AvailableUnits(userID, models.OrderItem{
+		ProductID: productID,
+		CatalogID: catalogID,
+		RTOCode:   rtoCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(units) == 0 {
+		return nil, fmt.Errorf("product %s: %w", productID, ErrRTOUnitNotFound)
+	}
+	unitPrice := units[0].Price
+
+	var item models.CartItem
+	err = s.db.Where("user_id = ? AND product_id = ?", userID, productID).First(&item).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	if err == gorm.ErrRecordNotFound {
+		item = models.CartItem{
+			UserID:    userID,
+			ProductID: productID,
+			CatalogID: catalogID,
+			RTOCode:   rtoCode,
+			Quantity:  quantity,
+			UnitPrice: unitPrice,
+		}
+		if err := s.db.Create(&item).Error; err != nil {
+			return nil, fmt.Errorf("failed to add cart item: %w", err)
+		}
+		return &item, nil
+	}
+
+	item.Quantity += quantity
+	item.RTOCode = rtoCode
+	item.UnitPrice = unitPrice
+	if err := s.db.Save(&item).Error; err != nil {
+		return nil, fmt.Errorf("failed to update cart item: %w", err)
+	}
+	return &item, nil
+}
+
+// UpdateItem sets the quantity of a product already in the cart
+func (s *CartService) UpdateItem(userID, productID string, quantity int) (*models.CartItem, error) {
+	var item models.CartItem
+	err := s.db.Where("user_id = ? AND product_id = ?", userID, productID).First(&item).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrCartItemNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	item.Quantity = quantity
+	if err := s.db.Save(&item).Error; err != nil {
+		return nil, fmt.Errorf("failed to update cart item: %w", err)
+	}
+	return &item, nil
+}
+
+// RemoveItem removes a product from the cart
+func (s *CartService) RemoveItem(userID, productID string) error {
+	result := s.db.Where("user_id = ? AND product_id = ?", userID, productID).Delete(&models.CartItem{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to remove cart item: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrCartItemNotFound
+	}
+	return nil
+}
+
+// Checkout turns the cart into a single order.
+// Prices are re-read from price_product_info and items that are no longer
+// available, or cannot be delivered to the address from the code holding
+// them, are removed from the cart and reported instead of ordered.
+func (s *CartService) Checkout(userID, userCode, orderID string, address *models.Address) (*models.CheckoutResult, error) {
+	var cartItems []models.CartItem
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&cartItems).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	result := &models.CheckoutResult{
+		RemovedItems: []models.CheckoutRemovedItem{},
+		PriceChanges: []models.CheckoutPriceChange{},
+	}
+
+	var orderItems []models.OrderItem
+	var removedProductIDs []string
+	for _, cartItem := range cartItems {
+		orderItem := models.OrderItem{
+			ProductID: cartItem.ProductID,
+			CatalogID: cartItem.CatalogID,
+			Quantity:  cartItem.Quantity,
+			RTOCode:   cartItem.RTOCode,
+		}
+		if orderItem.RTOCode == "" {
+			orderItem.RTOCode = userCode
+		}
+		if orderItem.RTOCode == "" {
+			return nil, fmt.Errorf("product %s: %w", cartItem.ProductID, ErrNoRTOCode)
+		}
+
+		err := s.addressService.CheckServiceable(orderItem.RTOCode, address)
+		if errors.Is(err, ErrAddressNotServiceable) {
+			result.RemovedItems = append(result.RemovedItems, removedCartItem(cartItem, CheckoutReasonNotServiceable))
+			removedProductIDs = append(removedProductIDs, cartItem.ProductID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		units, err := s.orderService.AvailableUnits(userID, orderItem)
+		if errors.Is(err, ErrProductNotFound) {
+			result.RemovedItems = append(result.RemovedItems, removedCartItem(cartItem, CheckoutReasonUnavailable))
+			removedProductIDs = append(removedProductIDs, cartItem.ProductID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(units) < cartItem.Quantity {
+			result.RemovedItems = append(result.RemovedItems, removedCartItem(cartItem, CheckoutReasonSold))
+			removedProductIDs = append(removedProductIDs, cartItem.ProductID)
+			continue
+		}
+
+		// The order charges each unit its own markdown; the cart tracks the
+		// price of the unit it was added at
+		currentPrice := units[0].Price
+		if currentPrice != cartItem.UnitPrice {
+			result.PriceChanges = append(result.PriceChanges, models.CheckoutPriceChange{
+				ProductID: cartItem.ProductID,
+				OldPrice:  cartItem.UnitPrice,
+				NewPrice:  currentPrice,
+			})
+		}
+
+		orderItems = append(orderItems, orderItem)
+	}
+
+	if len(removedProductIDs) > 0 {
+		err := s.db.Where("user_id = ? AND product_id IN ?", userID, removedProductIDs).Delete(&models.CartItem{}).Error
+		if err != nil {
+			fmt.Printf("Warning: Failed to remove unavailable items from cart: %v\n", err)
+		}
+	}
+
+	if len(orderItems) == 0 {
+		return result, ErrCartEmpty
+	}
+
+	order, err := s.orderService.CreateOrder(CreateOrderInput{
+		OrderID:   orderID,
+		UserID:    userID,
+		UserCode:  userCode,
+		Items:     orderItems,
+		Address:   address,
+		ClearCart: true,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Order = order
+	return result, nil
+}
+
+// removedCartItem builds the checkout report entry for a dropped cart item
+func removedCartItem(item models.CartItem, reason string) models.CheckoutRemovedItem {
+	return models.CheckoutRemovedItem{
+		ProductID: item.ProductID,
+		CatalogID: item.CatalogID,
+		Reason:    reason,
+	}
+}
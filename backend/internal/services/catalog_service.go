@@ -13,49 +13,54 @@ import (
 
 // CatalogService handles catalog-related operations
 type CatalogService struct {
-	db *gorm.DB
+	db                  *gorm.DB
+	rtoAgeService       *RTOAgeService
+	rtoNeighbourService *RTONeighbourService
+	trendingService     *TrendingService
 }
 
 // NewCatalogService creates a new catalog service
 func NewCatalogService() *CatalogService {
 	return &CatalogService{
-		db: configs.DB,
+		db:                  configs.DB,
+		rtoAgeService:       NewRTOAgeService(),
+		rtoNeighbourService: NewRTONeighbourService(),
+		trendingService:     NewTrendingService(),
 	}
 }
 
-// GetCatalogData fetches catalog data for a user
+// GetCatalogData fetches catalog data for a user. The RTO units come from
+// the first tier of the fallback chain that has any: the user's own code,
+// the nearby codes in their city, the codes in their state, and finally the
+// trending catalogs. CatalogMeta.Source reports the tier used.
 func (s *CatalogService) GetCatalogData(userID string) (*models.CatalogResponse, error) {
 	var catalogIDs []string
-	var source string
-	var userCode string
 
-	// Step 1: Try to get user's code from user_mapping table
-	userCode, err := s.getUserCode(userID)
+	// Step 1: Try to get user's code, city and state from user_mapping table
+	userMapping, err := s.getUserMapping(userID)
 	if err != nil {
-		fmt.Printf("Warning: Failed to get user code: %v. Using fallback catalog IDs.\n", err)
-		catalogIDs = s.getCatalogIDs()
-		source = "fallback_catalog_ids_with_ranking"
-		userCode = "" // Set empty string if not found
+		fmt.Printf("Warning: Failed to get user mapping: %v. Using fallback catalog IDs.\n", err)
+	}
+	userCode := userMapping.Code
+
+	// Step 2: Try to get RTO units near the user, widening the area tier by tier
+	rtoUnits, source := s.getRTOUnitsWithNeighbourFallback(userMapping)
+	if len(rtoUnits) > 0 {
+		catalogIDs = RTOCatalogIDs(rtoUnits)
 	} else {
-		// Step 2: Try to get catalog IDs from RTO API based on user's code
-		rtoService := NewRTOService()
-		catalogIDs = rtoService.GetCatalogIDsFromRTOWithFallback(userCode)
-
-		if len(catalogIDs) == 0 {
-			fmt.Printf("Warning: No catalog IDs from RTO API. Using fallback catalog IDs.\n")
-			catalogIDs = s.getCatalogIDs()
-			source = "fallback_catalog_ids_with_ranking"
-		} else {
-			source = "rto_api_with_ranking"
+		if err == nil {
+			fmt.Printf("Warning: No RTO units near user %s. Using fallback catalog IDs.\n", userID)
 		}
+		catalogIDs, source = s.trendingService.FallbackCatalogIDs(userID, userMapping.City)
 	}
 
 	// Step 3: Get ranked catalog IDs from ranking service
 	rankingService := NewRankingService()
 	rankedCatalogIDs := rankingService.GetRankedCatalogIDsWithFallback(catalogIDs, userID)
 
-	// Step 4: Query price_product_info table for the ranked catalog IDs
-	catalogProducts, err := s.getProductInfoByCatalogIDs(rankedCatalogIDs)
+	// Step 4: Query price_product_info table for the ranked catalog IDs,
+	// pricing and hiding RTO units by their age
+	catalogProducts, err := s.getProductInfoByCatalogIDs(rankedCatalogIDs, rtoUnits)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product info: %w", err)
 	}
@@ -80,51 +85,62 @@ func (s *CatalogService) GetCatalogData(userID string) (*models.CatalogResponse,
 	return response, nil
 }
 
-// getUserCode fetches the user's code from user_mapping table
-func (s *CatalogService) getUserCode(userID string) (string, error) {
+// getUserMapping fetches the user's code, city and state from user_mapping table
+func (s *CatalogService) getUserMapping(userID string) (models.UserMapping, error) {
 	var userMapping models.UserMapping
 
 	result := s.db.Where("user_id = ?", userID).First(&userMapping)
 	if result.Error != nil {
-		return "", fmt.Errorf("failed to find user mapping for user_id %s: %w", userID, result.Error)
+		return models.UserMapping{}, fmt.Errorf("failed to find user mapping for user_id %s: %w", userID, result.Error)
 	}
 
-	if userMapping.Code == "" {
-		return "", fmt.Errorf("no code found for user_id %s", userID)
+	fmt.Printf("Found code '%s' in %s, %s for user %s\n", userMapping.Code, userMapping.City, userMapping.State, userID)
+	return userMapping, nil
+}
+
+// getRTOUnitsWithNeighbourFallback returns the RTO units of the first tier
+// that has any, and the catalog source naming that tier
+func (s *CatalogService) getRTOUnitsWithNeighbourFallback(userMapping models.UserMapping) (models.RTOProducts, string) {
+	rtoService := NewRTOService()
+
+	if userMapping.Code != "" {
+		if units := rtoService.GetRTOUnitsWithFallback(userMapping.Code); len(units) > 0 {
+			return units, models.CatalogSourceUserCode
+		}
 	}
 
-	fmt.Printf("Found code '%s' for user %s\n", userMapping.Code, userID)
-	return userMapping.Code, nil
-}
+	tiers := []struct {
+		source    string
+		neighbour func(models.UserMapping) ([]string, error)
+	}{
+		{models.CatalogSourceCityNeighbours, s.rtoNeighbourService.CityNeighbours},
+		{models.CatalogSourceStateCodes, s.rtoNeighbourService.StateCodes},
+	}
 
-// getCatalogIDs returns the list of top 100 catalog IDs (fallback method)
-func (s *CatalogService) getCatalogIDs() []string {
-	return []string{
-		"647628", "3446585", "279116", "1610750", "1929554",
-		"1222068", "3948449", "329972", "3310138", "381394",
-		"258058", "2566266", "874707", "794925", "340768",
-		"588251", "1918153", "1235372", "173571", "339856",
-		"2764020", "2356994", "4057058", "313798", "373563",
-		"276440", "89085", "271993", "1426675", "689640",
-		"2597747", "3224910", "582131", "721729", "1701047",
-		"2489323", "692563", "2309360", "3764919", "535354",
-		"278790", "3011754", "633737", "454653", "491521",
-		"508493", "600594", "505419", "505884", "933930",
-		"340457", "1161355", "939873", "1034004", "939873",
-		"251580", "2260285", "998799", "1291857", "1318580",
-		"1087236", "205522", "152045", "2107126", "1039513",
-		"3546354", "467602", "368361", "3943714", "3873515",
-		"1311926", "2811468", "870959", "2321705", "642439",
-		"79349", "999423", "506203", "713681", "1606309",
-		"636532", "57790", "761239", "756322", "781576",
-		"801433", "754541", "170989", "801666", "800622",
-		"801161", "534900", "4106562", "83457", "1822307",
-		"536092", "564252", "3195474", "29187", "4114942",
+	for _, tier := range tiers {
+		codes, err := tier.neighbour(userMapping)
+		if err != nil {
+			fmt.Printf("Warning: Failed to find codes for %s: %v\n", tier.source, err)
+			continue
+		}
+
+		var units models.RTOProducts
+		for _, code := range codes {
+			units = append(units, rtoService.GetRTOUnitsWithFallback(code)...)
+		}
+		if len(units) > 0 {
+			fmt.Printf("Serving %d RTO units from codes %v (%s)\n", len(units), codes, tier.source)
+			return units, tier.source
+		}
 	}
+
+	return nil, models.CatalogSourceGlobal
 }
 
-// getProductInfoByCatalogIDs queries the price_product_info table for given catalog IDs
-func (s *CatalogService) getProductInfoByCatalogIDs(catalogIDs []string) ([]models.CatalogProduct, error) {
+// getProductInfoByCatalogIDs queries the price_product_info table for given catalog IDs.
+// Products held as the given RTO units are marked down by age, and left out
+// once every unit has expired.
+func (s *CatalogService) getProductInfoByCatalogIDs(catalogIDs []string, rtoUnits models.RTOProducts) ([]models.CatalogProduct, error) {
 	var priceProductInfos []models.PriceProductInfo
 
 	// Debug logging
@@ -139,18 +155,52 @@ func (s *CatalogService) getProductInfoByCatalogIDs(catalogIDs []string) ([]mode
 
 	fmt.Printf("Found %d products in price_product_info table for the given catalog IDs\n", len(priceProductInfos))
 
+	// Group the RTO units by product so each product is aged by its own units
+	unitsByProduct := make(map[int64]models.RTOProducts)
+	for _, unit := range rtoUnits {
+		unitsByProduct[unit.ProductID] = append(unitsByProduct[unit.ProductID], unit)
+	}
+
+	var policies RTOAgePolicies
+	if len(unitsByProduct) > 0 {
+		loaded, err := s.rtoAgeService.LoadPolicies()
+		if err != nil {
+			fmt.Printf("Warning: Failed to load RTO age policies: %v. Using default policy.\n", err)
+		}
+		policies = loaded
+	}
+
 	// Convert PriceProductInfo to CatalogProduct
+	now := time.Now()
 	var catalogProducts []models.CatalogProduct
+	hidden := 0
 	for _, priceProductInfo := range priceProductInfos {
-		catalogProduct := s.convertPriceProductInfoToCatalogProduct(priceProductInfo)
+		var unit *models.RTOProduct
+		var unitAge *RTOUnitAge
+		if productID, ok := parseRTOUnitProductID(priceProductInfo.ProductID); ok && len(unitsByProduct[productID]) > 0 {
+			policy := policies.For(priceProductInfo.Category)
+			listedUnit, age, listed := s.rtoAgeService.ListedUnit(policy, unitsByProduct[productID], now)
+			if !listed {
+				hidden++
+				continue
+			}
+			unit, unitAge = &listedUnit, age
+		}
+
+		catalogProduct := s.convertPriceProductInfoToCatalogProduct(priceProductInfo, unit, unitAge, now)
 		catalogProducts = append(catalogProducts, catalogProduct)
 	}
 
+	if hidden > 0 {
+		fmt.Printf("Hid %d products whose RTO units have expired\n", hidden)
+	}
+
 	return catalogProducts, nil
 }
 
-// convertPriceProductInfoToCatalogProduct converts PriceProductInfo to CatalogProduct
-func (s *CatalogService) convertPriceProductInfoToCatalogProduct(priceProductInfo models.PriceProductInfo) models.CatalogProduct {
+// convertPriceProductInfoToCatalogProduct converts PriceProductInfo to CatalogProduct,
+// naming the RTO unit on offer and applying its age when there is one
+func (s *CatalogService) convertPriceProductInfoToCatalogProduct(priceProductInfo models.PriceProductInfo, unit *models.RTOProduct, unitAge *RTOUnitAge, now time.Time) models.CatalogProduct {
 	// Generate image URL from the images field or catalog_id
 	imageURL := s.generateImageURL(priceProductInfo.CatalogID, priceProductInfo.Images)
 
@@ -161,9 +211,13 @@ func (s *CatalogService) convertPriceProductInfoToCatalogProduct(priceProductInf
 	}
 
 	// Generate price and discount based on meesho_price_with_shipping and supplier_listed_price
-	price, originalPrice, discount, discountPercent := s.generatePriceFromPriceProductInfo(priceProductInfo)
+	markdownPercent := 0
+	if unitAge != nil {
+		markdownPercent = unitAge.MarkdownPercent
+	}
+	price, originalPrice, discount, discountPercent := s.generatePriceFromPriceProductInfo(priceProductInfo, markdownPercent)
 
-	return models.CatalogProduct{
+	catalogProduct := models.CatalogProduct{
 		CatalogID:       priceProductInfo.CatalogID,
 		ProductID:       priceProductInfo.ProductID,
 		ImageURL:        imageURL,
@@ -175,6 +229,20 @@ func (s *CatalogService) convertPriceProductInfoToCatalogProduct(priceProductInf
 		Discount:        discount,
 		DiscountPercent: discountPercent,
 	}
+
+	// Orders for the product claim this unit from the code holding it
+	if unit != nil {
+		catalogProduct.RTOCode = unit.Code
+	}
+
+	if unitAge != nil {
+		expiresAt := unitAge.ExpiresAt
+		catalogProduct.AgeMarkdownPercent = unitAge.MarkdownPercent
+		catalogProduct.ExpiresAt = &expiresAt
+		catalogProduct.ExpiresIn = ExpiresInHint(expiresAt, now)
+	}
+
+	return catalogProduct
 }
 
 // generateImageURL generates image URL from catalog_id and images field
@@ -204,9 +272,9 @@ func (s *CatalogService) generateImageURL(catalogID, images string) string {
 }
 
 // generatePriceFromPriceProductInfo generates price and discount based on meesho_price_with_shipping and supplier_listed_price
-func (s *CatalogService) generatePriceFromPriceProductInfo(priceProductInfo models.PriceProductInfo) (string, string, string, int) {
-	// Use supplier_listed_price as the actual price (what customers pay)
-	actualPrice := priceProductInfo.SupplierListedPrice
+func (s *CatalogService) generatePriceFromPriceProductInfo(priceProductInfo models.PriceProductInfo, markdownPercent int) (string, string, string, int) {
+	// Use supplier_listed_price less any age markdown as the actual price (what customers pay)
+	actualPrice := EffectivePrice(priceProductInfo.SupplierListedPrice, markdownPercent)
 
 	// Use meesho_price_with_shipping as the original price (strikethrough price)
 	originalPrice := priceProductInfo.MeeshoPriceWithShipping
@@ -241,7 +309,7 @@ func (s *CatalogService) GetCatalogDataByIDs(catalogIDs []string, userID string)
 	rankedCatalogIDs := rankingService.GetRankedCatalogIDsWithFallback(validIDs, userID)
 
 	// Query price_product_info table for the ranked catalog IDs
-	catalogProducts, err := s.getProductInfoByCatalogIDs(rankedCatalogIDs)
+	catalogProducts, err := s.getProductInfoByCatalogIDs(rankedCatalogIDs, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product info: %w", err)
 	}
@@ -324,3 +392,8 @@ func (s *CatalogService) TruncateCaption(caption string, length int) string {
 	}
 	return caption
 }
+
+// RecordCatalogClick records a user opening a catalog for the trending job
+func (s *CatalogService) RecordCatalogClick(userID, catalogID string) error {
+	return s.trendingService.RecordClick(userID, catalogID)
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"meesho-clone/internal/models"
+)
+
+// CODPaymentProvider collects cash from the customer on delivery
+type CODPaymentProvider struct{}
+
+// NewCODPaymentProvider creates a new cash-on-delivery provider
+func NewCODPaymentProvider() *CODPaymentProvider {
+	return &CODPaymentProvider{}
+}
+
+// Name identifies the provider
+func (p *CODPaymentProvider) Name() string {
+	return "cod"
+}
+
+// CreateIntent records that cash will be collected; nothing is charged up front
+func (p *CODPaymentProvider) CreateIntent(req PaymentIntentRequest) (*PaymentIntent, error) {
+	return &PaymentIntent{
+		ProviderRef: "cod_" + req.PaymentID,
+		Status:      models.PaymentStatusPending,
+	}, nil
+}
+
+// Confirm marks the cash as collected by the courier
+func (p *CODPaymentProvider) Confirm(providerRef string) (*PaymentIntent, error) {
+	return &PaymentIntent{
+		ProviderRef: providerRef,
+		Status:      models.PaymentStatusSucceeded,
+	}, nil
+}
+
+// Status reports the cash as not yet collected; only the courier's
+// delivery confirms it
+func (p *CODPaymentProvider) Status(providerRef string) (*PaymentIntent, error) {
+	return &PaymentIntent{
+		ProviderRef: providerRef,
+		Status:      models.PaymentStatusPending,
+	}, nil
+}
+
+// Refund records a cash refund handed back to the customer
+func (p *CODPaymentProvider) Refund(providerRef string, amount float64) (*PaymentRefund, error) {
+	return &PaymentRefund{
+		RefundRef: "cod_refund_" + providerRef,
+		Amount:    amount,
+	}, nil
+}
+
+// VerifyWebhook is not supported for cash on delivery
+func (p *CODPaymentProvider) VerifyWebhook(payload []byte, signature string) (*PaymentWebhookEvent, error) {
+	return nil, ErrWebhookNotSupported
+}
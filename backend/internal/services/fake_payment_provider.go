@@ -0,0 +1,146 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meesho-clone/internal/models"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fakeGatewayWebhookTolerance bounds how old a signed webhook may be
+const fakeGatewayWebhookTolerance = 5 * time.Minute
+
+// FakePaymentProvider is a deterministic stand-in for an online payment gateway.
+// Provider references are derived from the payment ID, confirmations always
+// succeed and webhooks are signed with HMAC-SHA256 using a shared secret,
+// which makes it usable for local development and tests only.
+type FakePaymentProvider struct {
+	webhookSecret []byte
+	now           func() time.Time
+}
+
+// NewFakePaymentProvider creates a new fake gateway. The webhook secret is
+// read from FAKE_GATEWAY_WEBHOOK_SECRET and is required.
+func NewFakePaymentProvider() (*FakePaymentProvider, error) {
+	secret := os.Getenv("FAKE_GATEWAY_WEBHOOK_SECRET")
+	if secret == "" {
+		return nil, errors.New("FAKE_GATEWAY_WEBHOOK_SECRET is not set")
+	}
+
+	return &FakePaymentProvider{
+		webhookSecret: []byte(secret),
+		now:           time.Now,
+	}, nil
+}
+
+// Name identifies the provider
+func (p *FakePaymentProvider) Name() string {
+	return "fake_gateway"
+}
+
+// CreateIntent registers a payment that the customer still has to complete
+func (p *FakePaymentProvider) CreateIntent(req PaymentIntentRequest) (*PaymentIntent, error) {
+	if req.Amount < 0 {
+		return nil, fmt.Errorf("invalid payment amount %.2f", req.Amount)
+	}
+
+	return &PaymentIntent{
+		ProviderRef: p.providerRef(req.PaymentID),
+		Status:      models.PaymentStatusPending,
+		NextAction:  fmt.Sprintf("/api/v1/payments/%s/confirm", req.PaymentID),
+	}, nil
+}
+
+// Confirm completes the payment
+func (p *FakePaymentProvider) Confirm(providerRef string) (*PaymentIntent, error) {
+	return &PaymentIntent{
+		ProviderRef: providerRef,
+		Status:      models.PaymentStatusSucceeded,
+	}, nil
+}
+
+// Status reports the payment as still waiting for the customer; the fake
+// gateway only learns of a payment when it is confirmed
+func (p *FakePaymentProvider) Status(providerRef string) (*PaymentIntent, error) {
+	return &PaymentIntent{
+		ProviderRef: providerRef,
+		Status:      models.PaymentStatusPending,
+	}, nil
+}
+
+// Refund returns the full amount
+func (p *FakePaymentProvider) Refund(providerRef string, amount float64) (*PaymentRefund, error) {
+	return &PaymentRefund{
+		RefundRef: "fake_rfnd_" + strings.TrimPrefix(providerRef, "fake_pi_"),
+		Amount:    amount,
+	}, nil
+}
+
+// VerifyWebhook checks a "t=<unix>,v1=<hex hmac>" signature over "<t>.<payload>"
+func (p *FakePaymentProvider) VerifyWebhook(payload []byte, signature string) (*PaymentWebhookEvent, error) {
+	var timestamp, sig string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			sig = value
+		}
+	}
+	if timestamp == "" || sig == "" {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidWebhookSignature
+	}
+	age := p.now().Sub(time.Unix(unix, 0))
+	if age > fakeGatewayWebhookTolerance || age < -fakeGatewayWebhookTolerance {
+		return nil, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidWebhookSignature)
+	}
+
+	expected := p.sign(timestamp, payload)
+	provided, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(expected, provided) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var event PaymentWebhookEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	return &event, nil
+}
+
+// SignWebhook produces the signature header for a payload, for local tooling
+func (p *FakePaymentProvider) SignWebhook(payload []byte) string {
+	timestamp := strconv.FormatInt(p.now().Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(p.sign(timestamp, payload)))
+}
+
+// sign computes the HMAC over the timestamp and payload
+func (p *FakePaymentProvider) sign(timestamp string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.webhookSecret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// providerRef derives a stable reference from the payment ID
+func (p *FakePaymentProvider) providerRef(paymentID string) string {
+	sum := sha256.Sum256([]byte(paymentID))
+	return "fake_pi_" + hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrIdempotencyInFlight is returned when a request with the same key is still being processed
+	ErrIdempotencyInFlight = errors.New("a request with this idempotency key is already in progress")
+
+	// ErrIdempotencyKeyReused is returned when a key is reused with a different request body
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+
+	// ErrIdempotencyLeaseLost is returned when a retry took the key over
+	// because the lease of the request holding it ran out
+	ErrIdempotencyLeaseLost = errors.New("idempotency key was taken over by a retry")
+)
+
+// IdempotencyService stores and replays responses keyed by (user_id, Idempotency-Key)
+type IdempotencyService struct {
+	db            *gorm.DB
+	window        time.Duration
+	lease         time.Duration
+	sweepInterval time.Duration
+}
+
+// IdempotencyLease is a request's claim on an idempotency key. Only the
+// request holding the latest generation may use or finalise the key.
+type IdempotencyLease struct {
+	recordID   uint
+	generation int
+	duration   time.Duration
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService() *IdempotencyService {
+	return &IdempotencyService{
+		db:            configs.DB,
+		window:        getEnvDuration("IDEMPOTENCY_WINDOW", 24*time.Hour),
+		lease:         getEnvDuration("IDEMPOTENCY_LEASE", 2*time.Minute),
+		sweepInterval: getEnvDuration("IDEMPOTENCY_SWEEP_INTERVAL", time.Hour),
+	}
+}
+
+// HashRequest returns a stable fingerprint of a request body
+func HashRequest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Begin claims the key for a new request.
+// It returns the lease when the caller should process the request, or the
+// stored record when a completed response is available for replay. An
+// in-progress claim whose lease has run out is taken over by the retry.
+func (s *IdempotencyService) Begin(userID, key, requestHash string) (*IdempotencyLease, *models.IdempotencyKey, error) {
+	// Two attempts: the second one runs after an expired record has been cleared
+	for attempt := 0; attempt < 2; attempt++ {
+		lockedUntil := time.Now().Add(s.lease)
+		record := models.IdempotencyKey{
+			UserID:         userID,
+			IdempotencyKey: key,
+			RequestHash:    requestHash,
+			Status:          models.IdempotencyStatusInProgress,
+			LockedUntil:     &lockedUntil,
+			LeaseGeneration: 1,
+			ExpiresAt:       time.Now().Add(s.window),
+		}
+
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return nil, nil, fmt.Errorf("failed to store idempotency key: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return &IdempotencyLease{recordID: record.ID, generation: record.LeaseGeneration, duration: s.lease}, nil, nil
+		}
+
+		var existing models.IdempotencyKey
+		err := s.db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&existing).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				// Removed between our insert and read, try again
+				continue
+			}
+			return nil, nil, fmt.Errorf("database error: %v", err)
+		}
+
+		if time.Now().After(existing.ExpiresAt) {
+			err := s.db.Where("id = ? AND expires_at < ?", existing.ID, time.Now()).Delete(&models.IdempotencyKey{}).Error
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to clear expired idempotency key: %w", err)
+			}
+			continue
+		}
+
+		if existing.RequestHash != requestHash {
+			return nil, nil, ErrIdempotencyKeyReused
+		}
+
+		if existing.Status != models.IdempotencyStatusCompleted {
+			lease, err := s.takeOver(existing, lockedUntil)
+			return lease, nil, err
+		}
+
+		return nil, &existing, nil
+	}
+
+	return nil, nil, ErrIdempotencyInFlight
+}
+
+// takeOver claims an in-progress record whose lease has run out, e.g. because
+// the request holding it crashed or stalled. The new lease generation fences
+// off the old request. It returns ErrIdempotencyInFlight while the lease is
+// still held or when another retry took it over first.
+func (s *IdempotencyService) takeOver(existing models.IdempotencyKey, lockedUntil time.Time) (*IdempotencyLease, error) {
+	now := time.Now()
+	if existing.LockedUntil != nil && now.Before(*existing.LockedUntil) {
+		return nil, ErrIdempotencyInFlight
+	}
+
+	generation := existing.LeaseGeneration + 1
+	result := s.db.Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status = ? AND lease_generation = ? AND (locked_until IS NULL OR locked_until < ?)",
+			existing.ID, models.IdempotencyStatusInProgress, existing.LeaseGeneration, now).
+		Updates(map[string]interface{}{
+			"locked_until":     lockedUntil,
+			"lease_generation": generation,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to take over idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrIdempotencyInFlight
+	}
+	return &IdempotencyLease{recordID: existing.ID, generation: generation, duration: s.lease}, nil
+}
+
+// hold checks inside tx that the lease was not taken over and extends it, so
+// the key stays with this request until tx commits. The record stays locked
+// until then, which makes a concurrent takeover wait for the outcome.
+func (l *IdempotencyLease) hold(tx *gorm.DB) error {
+	var record models.IdempotencyKey
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND status = ? AND lease_generation = ?", l.recordID, models.IdempotencyStatusInProgress, l.generation).
+		Limit(1).Find(&record)
+	if result.Error != nil {
+		return fmt.Errorf("database error: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdempotencyLeaseLost
+	}
+
+	err := tx.Model(&record).Update("locked_until", time.Now().Add(l.duration)).Error
+	if err != nil {
+		return fmt.Errorf("failed to extend idempotency lease: %w", err)
+	}
+	return nil
+}
+
+// Complete stores the response for a claimed key so that duplicates can be replayed.
+// Server errors are not stored; the key is released so the client can retry.
+// Nothing is stored once the lease was taken over by a retry.
+func (s *IdempotencyService) Complete(lease *IdempotencyLease, responseCode int, responseBody []byte) error {
+	if responseCode >= 500 {
+		return s.Release(lease)
+	}
+
+	err := s.db.Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status = ? AND lease_generation = ?", lease.recordID, models.IdempotencyStatusInProgress, lease.generation).
+		Updates(map[string]interface{}{
+			"status":        models.IdempotencyStatusCompleted,
+			"response_code": responseCode,
+			"response_body": string(responseBody),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release removes an in-progress claim without storing a response, unless
+// the lease was taken over by a retry
+func (s *IdempotencyService) Release(lease *IdempotencyLease) error {
+	err := s.db.Where("id = ? AND status = ? AND lease_generation = ?", lease.recordID, models.IdempotencyStatusInProgress, lease.generation).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// StartExpirySweeper periodically deletes keys past their window until the context is cancelled
+func (s *IdempotencyService) StartExpirySweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.DeleteExpired()
+			if err != nil {
+				fmt.Printf("Warning: Idempotency key sweep failed: %v\n", err)
+			} else if deleted > 0 {
+				fmt.Printf("Deleted %d expired idempotency keys\n", deleted)
+			}
+		}
+	}
+}
+
+// DeleteExpired removes the keys whose replay window has passed
+func (s *IdempotencyService) DeleteExpired() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
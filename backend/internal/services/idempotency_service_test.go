@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"meesho-clone/internal/models"
+	"testing"
+	"time"
+)
+
+func TestTakenOverLeaseCannotFinaliseTheKey(t *testing.T) {
+	db := openTestDB(t)
+	service := NewIdempotencyService()
+
+	stale, _, err := service.Begin("buyer", "key-1", "hash")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	db.Model(&models.IdempotencyKey{}).Where("id = ?", stale.recordID).Update("locked_until", time.Now().Add(-time.Second))
+
+	retry, _, err := service.Begin("buyer", "key-1", "hash")
+	if err != nil || retry == nil {
+		t.Fatalf("Begin after the lease ran out = %v, %v, want a new lease", retry, err)
+	}
+
+	if err := stale.hold(db); !errors.Is(err, ErrIdempotencyLeaseLost) {
+		t.Fatalf("hold with the stale lease returned %v, want %v", err, ErrIdempotencyLeaseLost)
+	}
+	if err := service.Complete(stale, 200, []byte(`{"stale":true}`)); err != nil {
+		t.Fatalf("Complete with the stale lease: %v", err)
+	}
+	if err := service.Complete(retry, 200, []byte(`{"stale":false}`)); err != nil {
+		t.Fatalf("Complete with the retry's lease: %v", err)
+	}
+
+	_, record, err := service.Begin("buyer", "key-1", "hash")
+	if err != nil || record == nil {
+		t.Fatalf("Begin after completion = %v, %v, want the stored response", record, err)
+	}
+	if record.ResponseBody != `{"stale":false}` {
+		t.Fatalf("stored response %s, want the retry's", record.ResponseBody)
+	}
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	db := openTestDB(t)
+	service := NewIdempotencyService()
+
+	for _, key := range []string{"old", "fresh"} {
+		if _, _, err := service.Begin("buyer", key, "hash"); err != nil {
+			t.Fatalf("Begin(%s): %v", key, err)
+		}
+	}
+	db.Model(&models.IdempotencyKey{}).Where("idempotency_key = ?", "old").Update("expires_at", time.Now().Add(-time.Minute))
+
+	deleted, err := service.DeleteExpired()
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpired() = %d, %v, want 1 deleted key", deleted, err)
+	}
+}
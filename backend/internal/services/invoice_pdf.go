@@ -0,0 +1,267 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"meesho-clone/internal/models"
+	"strings"
+)
+
+// A4 page layout in PDF points
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfTableRowSize = 14
+)
+
+// invoiceColumn describes a right-aligned numeric column of the line item table
+type invoiceColumn struct {
+	title string
+	right float64
+	value func(line models.InvoiceLine) string
+}
+
+var invoiceColumns = []invoiceColumn{
+	{"Qty", 262, func(l models.InvoiceLine) string { return fmt.Sprintf("%d", l.Quantity) }},
+	{"Price", 312, func(l models.InvoiceLine) string { return formatAmount(l.SupplierListedPrice) }},
+	{"Shipping", 362, func(l models.InvoiceLine) string { return formatAmount(l.Shipping) }},
+	{"Discount", 412, func(l models.InvoiceLine) string { return formatAmount(l.Discount) }},
+	{"Taxable", 462, func(l models.InvoiceLine) string { return formatAmount(l.TaxableValue) }},
+	{"CGST", 500, func(l models.InvoiceLine) string { return formatAmount(l.CGST) }},
+	{"SGST", 538, func(l models.InvoiceLine) string { return formatAmount(l.SGST) }},
+	{"Total", pdfPageWidth - pdfMargin, func(l models.InvoiceLine) string { return formatAmount(l.Total) }},
+}
+
+// pdfPage collects the content stream operators for a single page
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+// text draws a string with its baseline starting at (x, y)
+func (p *pdfPage) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escapePDFText(s))
+}
+
+// textRight draws a string so that it ends at x
+func (p *pdfPage) textRight(x, y, size float64, bold bool, s string) {
+	p.text(x-textWidth(s, size), y, size, bold, s)
+}
+
+// line draws a horizontal rule at y
+func (p *pdfPage) line(y float64) {
+	fmt.Fprintf(&p.content, "0.5 w %d %.2f m %d %.2f l S\n", pdfMargin, y, pdfPageWidth-pdfMargin, y)
+}
+
+// RenderInvoicePDF renders an invoice as a standalone PDF document.
+// Only the built-in Helvetica fonts are used so no assets are required.
+func RenderInvoicePDF(invoice *models.Invoice) []byte {
+	var pages []*pdfPage
+
+	page := &pdfPage{}
+	pages = append(pages, page)
+	y := float64(pdfPageHeight - pdfMargin)
+
+	page.text(pdfMargin, y, 18, true, "Tax Invoice")
+	page.textRight(pdfPageWidth-pdfMargin, y, 10, true, invoice.InvoiceNumber)
+	y -= 24
+
+	details := []string{
+		"Order ID: " + invoice.OrderID,
+		"Order date: " + invoice.OrderPlacedAt.In(invoiceLocation).Format("02 Jan 2006"),
+		"Invoice date: " + invoice.IssuedAt.In(invoiceLocation).Format("02 Jan 2006"),
+	}
+	if invoice.PaymentMethod != "" {
+		details = append(details, "Payment method: "+strings.ToUpper(invoice.PaymentMethod))
+	}
+	for _, detail := range details {
+		page.text(pdfMargin, y, 10, false, detail)
+		y -= 14
+	}
+	y -= 8
+
+	page.text(pdfMargin, y, 10, true, "Bill to")
+	y -= 14
+	if invoice.BillingName != "" {
+		page.text(pdfMargin, y, 10, false, invoice.BillingName)
+		y -= 14
+	}
+	for _, addressLine := range wrapText(invoice.BillingAddress, 90) {
+		page.text(pdfMargin, y, 10, false, addressLine)
+		y -= 14
+	}
+	y -= 10
+
+	drawHeader := func(p *pdfPage, y float64) float64 {
+		p.text(pdfMargin, y, 8, true, "Item")
+		for _, column := range invoiceColumns {
+			p.textRight(column.right, y, 8, true, column.title)
+		}
+		p.line(y - 4)
+		return y - pdfTableRowSize - 4
+	}
+
+	y = drawHeader(page, y)
+	for _, line := range invoice.Lines {
+		if y < pdfMargin+pdfTableRowSize*8 {
+			page = &pdfPage{}
+			pages = append(pages, page)
+			y = drawHeader(page, float64(pdfPageHeight-pdfMargin))
+		}
+
+		page.text(pdfMargin, y, 8, false, truncateText(line.Description, 40))
+		for _, column := range invoiceColumns {
+			page.textRight(column.right, y, 8, false, column.value(line))
+		}
+		y -= pdfTableRowSize
+	}
+	page.line(y + pdfTableRowSize - 4)
+	y -= 6
+
+	totals := []struct {
+		label  string
+		amount float64
+	}{
+		{"Subtotal", invoice.Subtotal},
+		{"Shipping", invoice.ShippingTotal},
+		{"Discount", -invoice.DiscountTotal},
+		{"Taxable value", invoice.TaxableValue},
+		{"CGST", invoice.CGSTTotal},
+		{"SGST", invoice.SGSTTotal},
+	}
+	for _, total := range totals {
+		page.text(380, y, 9, false, total.label)
+		page.textRight(pdfPageWidth-pdfMargin, y, 9, false, formatAmount(total.amount))
+		y -= 13
+	}
+	page.text(380, y, 11, true, "Grand total (INR)")
+	page.textRight(pdfPageWidth-pdfMargin, y, 11, true, formatAmount(invoice.GrandTotal))
+
+	footer := "All amounts are in INR and inclusive of GST. This is a computer generated invoice."
+	for i, p := range pages {
+		p.text(pdfMargin, pdfMargin-16, 7, false, footer)
+		p.textRight(pdfPageWidth-pdfMargin, pdfMargin-16, 7, false, fmt.Sprintf("Page %d of %d", i+1, len(pages)))
+	}
+
+	return writePDF(pages)
+}
+
+// writePDF serialises the pages into a PDF file with a cross-reference table
+func writePDF(pages []*pdfPage) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	writeObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// Objects 1-4 are the catalog, page tree and fonts; each page then
+	// takes two objects, the page itself followed by its content stream.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		writeObject(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+i*2,
+		))
+		content := page.content.String()
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return buf.Bytes()
+}
+
+// escapePDFText escapes a string for use in a PDF literal string.
+// Characters outside printable ASCII are replaced since the built-in fonts cannot draw them.
+func escapePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// textWidth approximates the width of a string in Helvetica at the given size
+func textWidth(s string, size float64) float64 {
+	var units float64
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			units += 556
+		case r == '.' || r == ',' || r == ' ':
+			units += 278
+		case r == '-':
+			units += 333
+		case r >= 'A' && r <= 'Z':
+			units += 667
+		default:
+			units += 556
+		}
+	}
+	return units * size / 1000
+}
+
+// formatAmount formats a currency amount with two decimals
+func formatAmount(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// truncateText shortens a string to at most max runes
+func truncateText(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// wrapText splits text into lines of at most width runes, breaking on spaces
+func wrapText(s string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		var current string
+		for _, word := range strings.Fields(paragraph) {
+			if current != "" && len([]rune(current))+1+len([]rune(word)) > width {
+				lines = append(lines, current)
+				current = ""
+			}
+			if current != "" {
+				current += " "
+			}
+			current += word
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvoiceNotAvailable is returned when an order is not in a state that can be invoiced
+var ErrInvoiceNotAvailable = errors.New("invoice not available for this order")
+
+// invoiceableStatuses are the order statuses for which an invoice may be issued.
+// Orders that are still awaiting payment or were cancelled never get one.
+var invoiceableStatuses = map[string]bool{
+	models.OrderStatusConfirmed:      true,
+	models.OrderStatusShipped:        true,
+	models.OrderStatusOutForDelivery: true,
+	models.OrderStatusDelivered:      true,
+	models.OrderStatusReturned:       true,
+}
+
+// invoiceLocation is the timezone used to decide the financial year of an invoice
+var invoiceLocation = time.FixedZone("IST", 5*60*60+30*60)
+
+// InvoiceService handles invoice generation
+type InvoiceService struct {
+	db      *gorm.DB
+	gstRate float64
+	prefix  string
+}
+
+// NewInvoiceService creates a new invoice service
+func NewInvoiceService() *InvoiceService {
+	prefix := os.Getenv("INVOICE_PREFIX")
+	if prefix == "" {
+		prefix = "INV"
+	}
+
+	return &InvoiceService{
+		db:      configs.DB,
+		gstRate: getEnvFloat("INVOICE_GST_RATE", 5),
+		prefix:  prefix,
+	}
+}
+
+// FinancialYear returns the Indian financial year (April to March) for a time, e.g. "2026-27"
+func FinancialYear(t time.Time) string {
+	t = t.In(invoiceLocation)
+	startYear := t.Year()
+	if t.Month() < time.April {
+		startYear--
+	}
+	return fmt.Sprintf("%d-%02d", startYear, (startYear+1)%100)
+}
+
+// GetInvoice returns the invoice issued for an order. Invoices are issued
+// when the order is confirmed, so there is none before that.
+func (s *InvoiceService) GetInvoice(orderID string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := s.db.Where("order_id = ?", orderID).First(&invoice).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInvoiceNotAvailable
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return &invoice, nil
+}
+
+// issue allocates the next invoice number to an order and stores its invoice.
+// The caller must hold the order's row lock so that only one invoice number
+// is consumed per order; an order that already has an invoice keeps it.
+func (s *InvoiceService) issue(tx *gorm.DB, order *models.Order) (*models.Invoice, error) {
+	var existing models.Invoice
+	err := tx.Where("order_id = ?", order.OrderID).First(&existing).Error
+	if err == nil {
+		return &existing, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	if !invoiceableStatuses[order.Status] {
+		return nil, fmt.Errorf("%w: order is %s", ErrInvoiceNotAvailable, order.Status)
+	}
+
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", order.OrderID).Order("id").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	now := time.Now()
+	financialYear := FinancialYear(now)
+	sequence, err := s.nextSequence(tx, financialYear)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := s.buildInvoice(tx, order, items)
+	invoice.FinancialYear = financialYear
+	invoice.Sequence = sequence
+	invoice.InvoiceNumber = fmt.Sprintf("%s/%s/%06d", s.prefix, financialYear, sequence)
+	invoice.PaymentMethod = s.paymentMethod(tx, order.OrderID)
+	invoice.IssuedAt = now
+
+	if err := tx.Create(invoice).Error; err != nil {
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+	return invoice, nil
+}
+
+// nextSequence allocates the next invoice number in a financial year
+func (s *InvoiceService) nextSequence(tx *gorm.DB, financialYear string) (int, error) {
+	counter := models.InvoiceSequence{FinancialYear: financialYear}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&counter).Error; err != nil {
+		return 0, fmt.Errorf("failed to initialise invoice sequence: %w", err)
+	}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("financial_year = ?", financialYear).
+		First(&counter).Error
+	if err != nil {
+		return 0, fmt.Errorf("database error: %v", err)
+	}
+
+	counter.LastValue++
+	err = tx.Model(&models.InvoiceSequence{}).
+		Where("financial_year = ?", financialYear).
+		Update("last_value", counter.LastValue).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance invoice sequence: %w", err)
+	}
+
+	return counter.LastValue, nil
+}
+
+// buildInvoice computes the line items and totals for an order.
+// Items are charged at their supplier listed price less the age markdown of
+// the unit, which is shown as a discount. The shipping cost is also shown
+// and waived as a discount because RTO units are already in the buyer's area. Prices are GST inclusive and the tax is
+// split equally into CGST and SGST.
+func (s *InvoiceService) buildInvoice(tx *gorm.DB, order *models.Order, items []models.OrderItem) *models.Invoice {
+	invoice := &models.Invoice{
+		OrderID:        order.OrderID,
+		UserID:         order.UserID,
+		BillingName:    order.ShippingName,
+		BillingAddress: order.ShippingAddress,
+		OrderPlacedAt:  order.CreatedAt,
+	}
+
+	lines := make(models.InvoiceLines, 0, len(items))
+	for _, item := range items {
+		description := "Product " + item.ProductID
+		var shippingPerUnit float64
+
+		var priceProductInfo models.PriceProductInfo
+		if err := tx.Where("product_id = ?", item.ProductID).First(&priceProductInfo).Error; err != nil {
+			fmt.Printf("Warning: No price info found for product %s: %v\n", item.ProductID, err)
+		} else {
+			if priceProductInfo.Name != "" {
+				description = priceProductInfo.Name
+			}
+			shippingPerUnit = priceProductInfo.ShippingRevenue
+		}
+
+		quantity := float64(item.Quantity)
+		listed := roundCurrency(item.UnitPrice * quantity)
+		charged := roundCurrency(EffectivePrice(item.UnitPrice, item.MarkdownPercent) * quantity)
+		shipping := roundCurrency(shippingPerUnit * quantity)
+		discount := roundCurrency(shipping + listed - charged)
+		total := roundCurrency(listed + shipping - discount)
+		taxable := roundCurrency(total / (1 + s.gstRate/100))
+		cgst := roundCurrency((total - taxable) / 2)
+		sgst := roundCurrency(total - taxable - cgst)
+
+		lines = append(lines, models.InvoiceLine{
+			ProductID:           item.ProductID,
+			CatalogID:           item.CatalogID,
+			Description:         description,
+			Quantity:            item.Quantity,
+			SupplierListedPrice: item.UnitPrice,
+			Shipping:            shipping,
+			Discount:            discount,
+			TaxableValue:        taxable,
+			GSTRate:             s.gstRate,
+			CGST:                cgst,
+			SGST:                sgst,
+			Total:               total,
+		})
+
+		invoice.Subtotal += listed
+		invoice.ShippingTotal += shipping
+		invoice.DiscountTotal += discount
+		invoice.TaxableValue += taxable
+		invoice.CGSTTotal += cgst
+		invoice.SGSTTotal += sgst
+		invoice.GrandTotal += total
+	}
+
+	invoice.Lines = lines
+	invoice.Subtotal = roundCurrency(invoice.Subtotal)
+	invoice.ShippingTotal = roundCurrency(invoice.ShippingTotal)
+	invoice.DiscountTotal = roundCurrency(invoice.DiscountTotal)
+	invoice.TaxableValue = roundCurrency(invoice.TaxableValue)
+	invoice.CGSTTotal = roundCurrency(invoice.CGSTTotal)
+	invoice.SGSTTotal = roundCurrency(invoice.SGSTTotal)
+	invoice.GrandTotal = roundCurrency(invoice.GrandTotal)
+	return invoice
+}
+
+// paymentMethod returns the method of the most recent payment for an order
+func (s *InvoiceService) paymentMethod(tx *gorm.DB, orderID string) string {
+	var payment models.Payment
+	if err := tx.Where("order_id = ?", orderID).Order("id DESC").First(&payment).Error; err != nil {
+		return ""
+	}
+	return payment.Method
+}
+
+// roundCurrency rounds an amount to two decimal places
+func roundCurrency(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// getEnvFloat reads a non-negative float from the environment
+func getEnvFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 {
+		fmt.Printf("Warning: Invalid %s %q. Using %g.\n", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
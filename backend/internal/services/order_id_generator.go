@@ -0,0 +1,132 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// orderIDPrefix is kept so existing order IDs and new ones look alike
+	orderIDPrefix = "MEESH"
+
+	// orderIDEpoch is the custom epoch (2024-01-01 UTC) in milliseconds
+	orderIDEpoch int64 = 1704067200000
+
+	orderIDNodeBits     = 10
+	orderIDSequenceBits = 12
+	orderIDMaxNode      = (1 << orderIDNodeBits) - 1
+	orderIDMaxSequence  = (1 << orderIDSequenceBits) - 1
+
+	// crockfordAlphabet is URL-safe and sorts in the same order as the values it encodes
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	orderIDEncodedLen = 13
+)
+
+// OrderIDGenerator generates snowflake-style order IDs.
+// Each ID packs a millisecond timestamp, a node ID and a per-millisecond
+// sequence into 64 bits, encoded as fixed-width Crockford base32 so that
+// IDs from a single node sort lexicographically in generation order.
+type OrderIDGenerator struct {
+	mu       sync.Mutex
+	nodeID   int64
+	lastMs   int64
+	sequence int64
+	now      func() time.Time
+}
+
+var (
+	defaultOrderIDGenerator     *OrderIDGenerator
+	defaultOrderIDGeneratorOnce sync.Once
+)
+
+// NewOrderIDGenerator creates a new order ID generator for the given node
+func NewOrderIDGenerator(nodeID int64) (*OrderIDGenerator, error) {
+	if nodeID < 0 || nodeID > orderIDMaxNode {
+		return nil, fmt.Errorf("order ID node must be between 0 and %d, got %d", orderIDMaxNode, nodeID)
+	}
+
+	return &OrderIDGenerator{
+		nodeID: nodeID,
+		now:    time.Now,
+	}, nil
+}
+
+// DefaultOrderIDGenerator returns the process-wide generator configured from ORDER_NODE_ID
+func DefaultOrderIDGenerator() *OrderIDGenerator {
+	defaultOrderIDGeneratorOnce.Do(func() {
+		var nodeID int64
+		if value := os.Getenv("ORDER_NODE_ID"); value != "" {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				fmt.Printf("Warning: Invalid ORDER_NODE_ID %q: %v. Using node 0.\n", value, err)
+			} else {
+				nodeID = parsed
+			}
+		}
+
+		generator, err := NewOrderIDGenerator(nodeID)
+		if err != nil {
+			fmt.Printf("Warning: %v. Using node 0.\n", err)
+			generator, _ = NewOrderIDGenerator(0)
+		}
+		defaultOrderIDGenerator = generator
+	})
+	return defaultOrderIDGenerator
+}
+
+// NextID returns the next order ID, e.g. MEESH01HQ3Z8K4M000
+func (g *OrderIDGenerator) NextID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	nowMs := g.currentMs()
+
+	// If the wall clock moved backwards keep issuing IDs on the last
+	// millisecond we saw so that IDs never go backwards.
+	if nowMs < g.lastMs {
+		nowMs = g.lastMs
+	}
+
+	if nowMs == g.lastMs {
+		g.sequence++
+		if g.sequence > orderIDMaxSequence {
+			// Sequence exhausted for this millisecond. Wait for the next one,
+			// or move on to it straight away while the clock is behind after
+			// a rollback rather than spinning until it catches up.
+			if g.currentMs() < g.lastMs {
+				nowMs = g.lastMs + 1
+			}
+			for nowMs <= g.lastMs {
+				nowMs = g.currentMs()
+			}
+			g.sequence = 0
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastMs = nowMs
+
+	id := (nowMs-orderIDEpoch)<<(orderIDNodeBits+orderIDSequenceBits) |
+		g.nodeID<<orderIDSequenceBits |
+		g.sequence
+
+	return orderIDPrefix + encodeCrockford(uint64(id))
+}
+
+// currentMs returns the current time in milliseconds since the Unix epoch
+func (g *OrderIDGenerator) currentMs() int64 {
+	return g.now().UnixMilli()
+}
+
+// encodeCrockford encodes a value as fixed-width Crockford base32
+func encodeCrockford(value uint64) string {
+	encoded := make([]byte, orderIDEncodedLen)
+	for i := orderIDEncodedLen - 1; i >= 0; i-- {
+		encoded[i] = crockfordAlphabet[value&31]
+		value >>= 5
+	}
+	return string(encoded)
+}
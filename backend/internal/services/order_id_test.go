@@ -0,0 +1,225 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a controllable clock. When tickEvery is set the clock moves
+// forward one millisecond every tickEvery reads.
+type fakeClock struct {
+	mu        sync.Mutex
+	now       time.Time
+	reads     int
+	tickEvery int
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.reads++
+	if c.tickEvery > 0 && c.reads%c.tickEvery == 0 {
+		c.now = c.now.Add(time.Millisecond)
+	}
+	return c.now
+}
+
+func (c *fakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// decodeOrderID splits an order ID into its timestamp, node and sequence
+func decodeOrderID(t *testing.T, id string) (ms, node, sequence int64) {
+	t.Helper()
+
+	encoded, ok := strings.CutPrefix(id, orderIDPrefix)
+	if !ok || len(encoded) != orderIDEncodedLen {
+		t.Fatalf("malformed order ID %q", id)
+	}
+
+	var value uint64
+	for _, ch := range encoded {
+		digit := strings.IndexRune(crockfordAlphabet, ch)
+		if digit < 0 {
+			t.Fatalf("order ID %q has invalid character %q", id, ch)
+		}
+		value = value<<5 | uint64(digit)
+	}
+
+	sequence = int64(value & orderIDMaxSequence)
+	node = int64(value>>orderIDSequenceBits) & orderIDMaxNode
+	ms = int64(value>>(orderIDNodeBits+orderIDSequenceBits)) + orderIDEpoch
+	return ms, node, sequence
+}
+
+func newTestOrderIDGenerator(t *testing.T, nodeID int64, clock *fakeClock) *OrderIDGenerator {
+	t.Helper()
+
+	generator, err := NewOrderIDGenerator(nodeID)
+	if err != nil {
+		t.Fatalf("NewOrderIDGenerator(%d): %v", nodeID, err)
+	}
+	if clock != nil {
+		generator.now = clock.Now
+	}
+	return generator
+}
+
+func TestNewOrderIDGeneratorRejectsInvalidNode(t *testing.T) {
+	for _, nodeID := range []int64{-1, orderIDMaxNode + 1} {
+		if _, err := NewOrderIDGenerator(nodeID); err == nil {
+			t.Errorf("NewOrderIDGenerator(%d) succeeded, want error", nodeID)
+		}
+	}
+}
+
+func TestOrderIDEncodesTimestampNodeAndSequence(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	generator := newTestOrderIDGenerator(t, 42, newFakeClock(start))
+
+	for want := int64(0); want < 3; want++ {
+		ms, node, sequence := decodeOrderID(t, generator.NextID())
+		if ms != start.UnixMilli() || node != 42 || sequence != want {
+			t.Fatalf("decoded (%d, %d, %d), want (%d, 42, %d)", ms, node, sequence, start.UnixMilli(), want)
+		}
+	}
+}
+
+func TestOrderIDsAreUniqueAndOrderedAcrossGoroutines(t *testing.T) {
+	workers, perWorker := 8, 250_000
+	if testing.Short() {
+		perWorker = 10_000
+	}
+
+	generator := newTestOrderIDGenerator(t, 7, nil)
+	results := make([][]string, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			ids := make([]string, perWorker)
+			for i := range ids {
+				ids[i] = generator.NextID()
+			}
+			results[w] = ids
+		}(w)
+	}
+	wg.Wait()
+
+	seen := make(map[string]struct{}, workers*perWorker)
+	for w, ids := range results {
+		for i, id := range ids {
+			if _, dup := seen[id]; dup {
+				t.Fatalf("duplicate order ID %s", id)
+			}
+			seen[id] = struct{}{}
+
+			// Each goroutine sees its own IDs strictly increasing
+			if i > 0 && id <= ids[i-1] {
+				t.Fatalf("worker %d: order ID %s not after %s", w, id, ids[i-1])
+			}
+		}
+	}
+	if len(seen) != workers*perWorker {
+		t.Fatalf("got %d unique IDs, want %d", len(seen), workers*perWorker)
+	}
+}
+
+func TestOrderIDsStayOrderedWhenClockMovesBackwards(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+	generator := newTestOrderIDGenerator(t, 1, clock)
+
+	before := generator.NextID()
+
+	clock.Set(start.Add(-5 * time.Second))
+	previous := before
+	for i := 0; i < 100; i++ {
+		id := generator.NextID()
+		if id <= previous {
+			t.Fatalf("order ID %s after rollback not after %s", id, previous)
+		}
+		ms, _, _ := decodeOrderID(t, id)
+		if ms != start.UnixMilli() {
+			t.Fatalf("order ID issued at %d during rollback, want last seen %d", ms, start.UnixMilli())
+		}
+		previous = id
+	}
+
+	clock.Set(start.Add(time.Second))
+	if id := generator.NextID(); id <= previous {
+		t.Fatalf("order ID %s after clock recovered not after %s", id, previous)
+	}
+}
+
+func TestOrderIDSequenceOverflowMovesToNextMillisecond(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+	generator := newTestOrderIDGenerator(t, 3, clock)
+
+	previous := ""
+	for i := 0; i <= orderIDMaxSequence; i++ {
+		id := generator.NextID()
+		if id <= previous {
+			t.Fatalf("order ID %s not after %s", id, previous)
+		}
+		previous = id
+	}
+	ms, _, sequence := decodeOrderID(t, previous)
+	if ms != start.UnixMilli() || sequence != orderIDMaxSequence {
+		t.Fatalf("last ID of the millisecond decoded as (%d, seq %d)", ms, sequence)
+	}
+
+	// The generator has to wait for the clock to reach the next millisecond
+	clock.tickEvery = 10
+	id := generator.NextID()
+	ms, _, sequence = decodeOrderID(t, id)
+	if id <= previous || ms != start.UnixMilli()+1 || sequence != 0 {
+		t.Fatalf("order ID after overflow decoded as (%d, seq %d), want (%d, seq 0)", ms, sequence, start.UnixMilli()+1)
+	}
+}
+
+func TestOrderIDSequenceOverflowDuringClockRollback(t *testing.T) {
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := newFakeClock(start)
+	generator := newTestOrderIDGenerator(t, 3, clock)
+
+	generator.NextID()
+	clock.Set(start.Add(-time.Minute))
+
+	// The clock stays behind, so the generator must not wait for it
+	done := make(chan []string)
+	go func() {
+		ids := make([]string, 3*(orderIDMaxSequence+1))
+		for i := range ids {
+			ids[i] = generator.NextID()
+		}
+		done <- ids
+	}()
+
+	select {
+	case ids := <-done:
+		for i := 1; i < len(ids); i++ {
+			if ids[i] <= ids[i-1] {
+				t.Fatalf("order ID %s not after %s", ids[i], ids[i-1])
+			}
+		}
+		ms, _, _ := decodeOrderID(t, ids[len(ids)-1])
+		if ms != start.UnixMilli()+3 {
+			t.Fatalf("last ID issued at %d, want %d", ms, start.UnixMilli()+3)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("NextID blocked on sequence overflow while the clock was behind")
+	}
+}
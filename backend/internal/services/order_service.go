@@ -0,0 +1,545 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrOrderNotFound is returned when no order matches the request
+	ErrOrderNotFound = errors.New("order not found")
+
+	// ErrInvalidOrderTransition is returned when the state machine does not allow a status change
+	ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+	// ErrNoRTOCode is returned when an ordered item cannot be tied to the RTO code holding it
+	ErrNoRTOCode = errors.New("no RTO code for the ordered item")
+)
+
+// orderTxAttempts bounds how often an order transaction is retried after a deadlock
+const orderTxAttempts = 3
+
+// OrderService handles order-related operations
+type OrderService struct {
+	db                 *gorm.DB
+	reservationService *ReservationService
+	rtoService         *RTOService
+	rtoAgeService      *RTOAgeService
+	invoiceService     *InvoiceService
+}
+
+// NewOrderService creates a new order service
+func NewOrderService() *OrderService {
+	return &OrderService{
+		db:                 configs.DB,
+		reservationService: NewReservationService(),
+		rtoService:         NewRTOService(),
+		rtoAgeService:      NewRTOAgeService(),
+		invoiceService:     NewInvoiceService(),
+	}
+}
+
+// CreateOrderInput holds the data needed to persist a new order
+type CreateOrderInput struct {
+	OrderID  string
+	UserID   string
+	UserCode string
+
+	// Items are ordered from the RTO code they name, or from UserCode when
+	// they name none. A sub order number picks one unit; otherwise any free
+	// units of the product are claimed, one order item per unit.
+	Items []models.OrderItem
+
+	// Address is the delivery address snapshotted onto the order
+	Address *models.Address
+
+	// ClearCart removes the ordered products from the user's cart in the same transaction
+	ClearCart bool
+
+	// Lease, when set, must still hold the request's Idempotency-Key; it is
+	// checked and extended in the same transaction
+	Lease *IdempotencyLease
+}
+
+// CreateOrder claims the RTO units and writes the order, its items and the
+// RTO drop intents inside a single transaction. Items that cannot be tied to
+// an RTO code are rejected with ErrNoRTOCode, and units that have expired
+// under their age policy are not sold. Each unit is charged its supplier
+// listed price less its own age markdown.
+func (s *OrderService) CreateOrder(input CreateOrderInput) (*models.Order, error) {
+	if len(input.Items) == 0 {
+		return nil, fmt.Errorf("order must contain at least one item")
+	}
+
+	// Look the units up in the RTO lists before opening the transaction
+	now := time.Now()
+	input.Items = append([]models.OrderItem(nil), input.Items...)
+	candidates := make([]listedUnits, 0, len(input.Items))
+	for i := range input.Items {
+		item := &input.Items[i]
+		if item.RTOCode == "" {
+			item.RTOCode = input.UserCode
+		}
+		if item.RTOCode == "" {
+			return nil, fmt.Errorf("product %s: %w", item.ProductID, ErrNoRTOCode)
+		}
+
+		listed, err := s.listedUnits(*item, item.Quantity, now)
+		if err != nil {
+			return nil, err
+		}
+		item.UnitPrice = listed.listedPrice
+		candidates = append(candidates, listed)
+	}
+
+	order := models.Order{
+		OrderID:       input.OrderID,
+		UserID:        input.UserID,
+		UserCode:      input.UserCode,
+		Status:        models.OrderStatusPlaced,
+		RTODropStatus: models.OutboxStatusPending,
+	}
+	if input.Address != nil {
+		order.AddressID = input.Address.AddressID
+		order.ShippingName = input.Address.Name
+		order.ShippingPhone = input.Address.Phone
+		order.ShippingAddress = input.Address.FormattedAddress()
+		order.ShippingPincode = input.Address.Pincode
+	}
+
+	create := func(tx *gorm.DB) error {
+		var items []models.OrderItem
+		var events []models.OutboxEvent
+		var total float64
+		order.ID = 0
+
+		if input.Lease != nil {
+			if err := input.Lease.hold(tx); err != nil {
+				return err
+			}
+		}
+
+		for i, item := range input.Items {
+			item.OrderID = input.OrderID
+
+			// Every unit becomes an order item of its own
+			for n := 0; n < item.Quantity; n++ {
+				claimed, err := s.reservationService.ClaimUnit(tx, input.UserID, item, candidates[i].units)
+				if err != nil {
+					return err
+				}
+				claimed.MarkdownPercent = candidates[i].markdownPercent(s.rtoAgeService, claimed.RTOOrderDate, now)
+
+				event, err := NewRTODropEvent(input.OrderID, claimed)
+				if err != nil {
+					return err
+				}
+
+				total += EffectivePrice(claimed.UnitPrice, claimed.MarkdownPercent)
+				items = append(items, claimed)
+				events = append(events, event)
+			}
+		}
+		order.TotalAmount = total
+
+		if err := tx.Omit("Items").Create(&order).Error; err != nil {
+			return fmt.Errorf("failed to create order: %w", err)
+		}
+
+		if err := tx.Create(&items).Error; err != nil {
+			return fmt.Errorf("failed to create order items: %w", err)
+		}
+
+		if err := tx.Create(&events).Error; err != nil {
+			return fmt.Errorf("failed to create outbox events: %w", err)
+		}
+
+		if err := s.recordStatusChange(tx, input.OrderID, "", order.Status, UserActor(input.UserID), ""); err != nil {
+			return err
+		}
+
+		if input.ClearCart {
+			productIDs := make([]string, 0, len(items))
+			for _, item := range items {
+				productIDs = append(productIDs, item.ProductID)
+			}
+			err := tx.Where("user_id = ? AND product_id IN ?", input.UserID, productIDs).Delete(&models.CartItem{}).Error
+			if err != nil {
+				return fmt.Errorf("failed to clear cart: %w", err)
+			}
+		}
+
+		order.Items = items
+		return nil
+	}
+
+	// Buyers racing for the same units can still deadlock; MySQL rolls the
+	// loser back and its order is retried against the winner's claims
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = s.db.Transaction(create)
+		if err == nil || attempt == orderTxAttempts || !isDeadlock(err) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// isDeadlock reports whether a transaction failed on a MySQL deadlock or lock wait timeout
+func isDeadlock(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == 1213 || mysqlErr.Number == 1205)
+}
+
+// listedUnits holds the units of an ordered product that are still listed,
+// oldest first, along with what they cost
+type listedUnits struct {
+	units       models.RTOProducts
+	policy      models.RTOAgePolicy
+	listedPrice float64
+}
+
+// markdownPercent returns the age markdown of the unit with the given order date
+func (l listedUnits) markdownPercent(ageService *RTOAgeService, orderDate string, now time.Time) int {
+	age, dated := ageService.UnitAge(l.policy, models.RTOProduct{OrderDate: orderDate}, now)
+	if !dated {
+		return 0
+	}
+	return age.MarkdownPercent
+}
+
+// listedUnits looks up the units of an item's product at its RTO code that
+// are still listed under the product's age policy. It fails with
+// ErrRTOUnitExpired when fewer than quantity are listed only because the
+// rest have expired.
+func (s *OrderService) listedUnits(item models.OrderItem, quantity int, now time.Time) (listedUnits, error) {
+	var priceProductInfo models.PriceProductInfo
+	err := s.db.Where("product_id = ?", item.ProductID).First(&priceProductInfo).Error
+	if err == gorm.ErrRecordNotFound {
+		return listedUnits{}, fmt.Errorf("%w: %s", ErrProductNotFound, item.ProductID)
+	}
+	if err != nil {
+		return listedUnits{}, fmt.Errorf("database error: %v", err)
+	}
+
+	policies, err := s.rtoAgeService.LoadPolicies()
+	if err != nil {
+		return listedUnits{}, err
+	}
+	policy := policies.For(priceProductInfo.Category)
+
+	units, err := s.rtoService.FindUnits(item.RTOCode, item.ProductID, item.SubOrderNum)
+	if err != nil {
+		return listedUnits{}, err
+	}
+	if len(units) < quantity {
+		return listedUnits{}, fmt.Errorf("product %s: %w", item.ProductID, ErrRTOUnitNotFound)
+	}
+
+	listable := s.rtoAgeService.ListableUnits(policy, units, now)
+	if len(listable) < quantity {
+		return listedUnits{}, fmt.Errorf("product %s: %w", item.ProductID, ErrRTOUnitExpired)
+	}
+
+	return listedUnits{
+		units:       listable,
+		policy:      policy,
+		listedPrice: priceProductInfo.SupplierListedPrice,
+	}, nil
+}
+
+// HoldItem reserves a listed unit of an item's product at its RTO code for
+// the user while they complete checkout
+func (s *OrderService) HoldItem(userID string, item models.OrderItem) (*models.InventoryReservation, error) {
+	if item.RTOCode == "" {
+		return nil, fmt.Errorf("product %s: %w", item.ProductID, ErrNoRTOCode)
+	}
+
+	listed, err := s.listedUnits(item, 1, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s.reservationService.Hold(userID, item.RTOCode, item.ProductID, item.CatalogID, listed.units)
+}
+
+// UnitQuote is a unit the user could buy right now and the price it is charged at
+type UnitQuote struct {
+	Unit  models.RTOProduct
+	Price float64
+}
+
+// AvailableUnits returns the listed units of an item's product at its RTO
+// code that the user could buy right now, oldest first, with their prices
+func (s *OrderService) AvailableUnits(userID string, item models.OrderItem) ([]UnitQuote, error) {
+	now := time.Now()
+	listed, err := s.listedUnits(item, 0, now)
+	if err != nil {
+		return nil, err
+	}
+
+	available := make([]UnitQuote, 0, len(listed.units))
+	for _, unit := range listed.units {
+		ok, err := s.reservationService.IsAvailable(userID, item.RTOCode, item.ProductID, unit.SubOrderNum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		markdown := listed.markdownPercent(s.rtoAgeService, unit.OrderDate, now)
+		available = append(available, UnitQuote{
+			Unit:  unit,
+			Price: EffectivePrice(listed.listedPrice, markdown),
+		})
+	}
+	return available, nil
+}
+
+// GetOrderByID retrieves an order and its items by order ID
+func (s *OrderService) GetOrderByID(orderID string) (*models.Order, error) {
+	var order models.Order
+	err := s.db.Preload("Items").Where("order_id = ?", orderID).First(&order).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return &order, nil
+}
+
+// GetOrdersByUserID retrieves all orders placed by a user, newest first
+func (s *OrderService) GetOrdersByUserID(userID string) ([]models.Order, error) {
+	var orders []models.Order
+	err := s.db.Preload("Items").Where("user_id = ?", userID).Order("created_at DESC").Find(&orders).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return orders, nil
+}
+
+// UserActor returns the history actor string for a user
+func UserActor(userID string) string {
+	return "user:" + userID
+}
+
+// StaffActor returns the history actor string for an ops or admin user
+func StaffActor(userID string) string {
+	return "staff:" + userID
+}
+
+// TransitionStatus moves an order to a new status if the state machine allows it
+func (s *OrderService) TransitionStatus(orderID, toStatus, actor, reason string) (*models.Order, error) {
+	var order *models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = s.transition(tx, orderID, toStatus, actor, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ChangeStatus moves an order to a new status on behalf of staff. Cancelled
+// and returned orders release their units and queue them back onto the RTO
+// list, like a cancellation by the customer.
+func (s *OrderService) ChangeStatus(orderID, toStatus, actor, reason string) (*models.Order, error) {
+	var order *models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch toStatus {
+		case models.OrderStatusCancelled:
+			order, err = s.cancel(tx, orderID, actor, reason)
+		case models.OrderStatusReturned:
+			order, err = s.returnOrder(tx, orderID, actor, reason)
+		default:
+			order, err = s.transition(tx, orderID, toStatus, actor, reason)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// CancelOrder cancels an order owned by the user and puts its units back into the RTO pool
+func (s *OrderService) CancelOrder(orderID, userID, reason string) (*models.Order, error) {
+	var order *models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.Order
+		err := tx.Where("order_id = ? AND user_id = ?", orderID, userID).First(&existing).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("database error: %v", err)
+		}
+
+		order, err = s.cancel(tx, orderID, UserActor(userID), reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// CancelOrderAsSystem cancels an order on behalf of an internal actor (e.g. a failed payment)
+func (s *OrderService) CancelOrderAsSystem(orderID, actor, reason string) (*models.Order, error) {
+	var order *models.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = s.cancel(tx, orderID, actor, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// cancel moves the order to CANCELLED, releases its units and queues their
+// return to the RTO list and the refund of its payments
+func (s *OrderService) cancel(tx *gorm.DB, orderID, actor, reason string) (*models.Order, error) {
+	order, err := s.transition(tx, orderID, models.OrderStatusCancelled, actor, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.releaseUnits(tx, order); err != nil {
+		return nil, err
+	}
+	if err := queueRefunds(tx, orderID, "order cancelled"); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// returnOrder moves the order to RETURNED once its units are back with us,
+// releases them and queues their return to the RTO list and the refund of
+// its payments
+func (s *OrderService) returnOrder(tx *gorm.DB, orderID, actor, reason string) (*models.Order, error) {
+	order, err := s.transition(tx, orderID, models.OrderStatusReturned, actor, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.releaseUnits(tx, order); err != nil {
+		return nil, err
+	}
+	if err := queueRefunds(tx, orderID, "order returned"); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// releaseUnits frees the units sold with an order and queues a restore
+// event for each of them
+func (s *OrderService) releaseUnits(tx *gorm.DB, order *models.Order) error {
+	orderID := order.OrderID
+	if err := s.reservationService.ReleaseForOrder(tx, orderID); err != nil {
+		return err
+	}
+
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	events := make([]models.OutboxEvent, 0, len(items))
+	for _, item := range items {
+		// Items of older orders only carry the code on the order
+		if item.RTOCode == "" {
+			item.RTOCode = order.UserCode
+		}
+		if item.RTOCode == "" {
+			continue
+		}
+
+		event, err := NewRTORestoreEvent(orderID, item)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	if len(events) > 0 {
+		if err := tx.Create(&events).Error; err != nil {
+			return fmt.Errorf("failed to create outbox events: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetStatusHistory returns every status change recorded for an order, oldest first
+func (s *OrderService) GetStatusHistory(orderID string) ([]models.OrderStatusHistory, error) {
+	var history []models.OrderStatusHistory
+	err := s.db.Where("order_id = ?", orderID).Order("created_at, id").Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return history, nil
+}
+
+// transition locks the order row, validates the status change and records it.
+// Confirming or delivering the order also issues its invoice.
+func (s *OrderService) transition(tx *gorm.DB, orderID, toStatus, actor, reason string) (*models.Order, error) {
+	var order models.Order
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderID).First(&order).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	if !models.CanTransitionOrderStatus(order.Status, toStatus) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidOrderTransition, order.Status, toStatus)
+	}
+
+	fromStatus := order.Status
+	if err := tx.Model(&order).Update("status", toStatus).Error; err != nil {
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+	order.Status = toStatus
+
+	if err := s.recordStatusChange(tx, orderID, fromStatus, toStatus, actor, reason); err != nil {
+		return nil, err
+	}
+
+	// The invoice number is allocated with the confirmation; delivery issues
+	// one for any confirmed order that is still without an invoice
+	if toStatus == models.OrderStatusConfirmed || toStatus == models.OrderStatusDelivered {
+		if _, err := s.invoiceService.issue(tx, &order); err != nil {
+			return nil, err
+		}
+	}
+
+	return &order, nil
+}
+
+// recordStatusChange appends an entry to the order status history
+func (s *OrderService) recordStatusChange(tx *gorm.DB, orderID, fromStatus, toStatus, actor, reason string) error {
+	entry := models.OrderStatusHistory{
+		OrderID:    orderID,
+		FromStatus: fromStatus,
+		ToStatus:   toStatus,
+		Actor:      actor,
+		Reason:     reason,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to record order status change: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"meesho-clone/internal/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// placeConfirmedOrder places an order for one seeded unit and confirms it
+func placeConfirmedOrder(t *testing.T, db *gorm.DB, service *OrderService) {
+	t.Helper()
+
+	t.Setenv("RTO_STORE", "db")
+	seedRTOList(t, db, "C1", 101, 1, "SO-1")
+
+	_, err := service.CreateOrder(CreateOrderInput{
+		OrderID: "ORDER_1",
+		UserID:  "buyer",
+		Items:   []models.OrderItem{{RTOCode: "C1", ProductID: "101", CatalogID: "900", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	if _, err := service.ChangeStatus("ORDER_1", models.OrderStatusConfirmed, "staff:ops", ""); err != nil {
+		t.Fatalf("confirm order: %v", err)
+	}
+}
+
+// assertUnitsReleased checks that the order no longer claims its unit and
+// that one restore event was queued for it
+func assertUnitsReleased(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	var claimed int64
+	db.Model(&models.InventoryReservation{}).Where("order_id = ? AND active_unit IS NOT NULL", "ORDER_1").Count(&claimed)
+	var restores int64
+	db.Model(&models.OutboxEvent{}).Where("order_id = ? AND event_type = ?", "ORDER_1", models.OutboxEventRTORestore).Count(&restores)
+	if claimed != 0 || restores != 1 {
+		t.Fatalf("order still claims %d units with %d restore events, want 0 claims and 1 restore", claimed, restores)
+	}
+}
+
+func TestStaffCancellationReleasesUnits(t *testing.T) {
+	db := openTestDB(t)
+	service := NewOrderService()
+	placeConfirmedOrder(t, db, service)
+
+	order, err := service.ChangeStatus("ORDER_1", models.OrderStatusCancelled, "staff:ops", "out of stock")
+	if err != nil {
+		t.Fatalf("ChangeStatus: %v", err)
+	}
+	if order.Status != models.OrderStatusCancelled {
+		t.Fatalf("order is %s, want %s", order.Status, models.OrderStatusCancelled)
+	}
+	assertUnitsReleased(t, db)
+}
+
+func TestReturnReleasesUnits(t *testing.T) {
+	db := openTestDB(t)
+	service := NewOrderService()
+	placeConfirmedOrder(t, db, service)
+
+	if _, err := service.ChangeStatus("ORDER_1", models.OrderStatusShipped, "staff:ops", ""); err != nil {
+		t.Fatalf("ship order: %v", err)
+	}
+	order, err := service.ChangeStatus("ORDER_1", models.OrderStatusReturned, "staff:ops", "refused at door")
+	if err != nil {
+		t.Fatalf("ChangeStatus: %v", err)
+	}
+	if order.Status != models.OrderStatusReturned {
+		t.Fatalf("order is %s, want %s", order.Status, models.OrderStatusReturned)
+	}
+	assertUnitsReleased(t, db)
+}
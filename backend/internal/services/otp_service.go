@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrOTPThrottled is returned when too many OTPs were requested recently
+	ErrOTPThrottled = errors.New("too many OTP requests")
+
+	// ErrOTPInvalid is returned when the submitted code does not match
+	ErrOTPInvalid = errors.New("invalid OTP")
+
+	// ErrOTPExpired is returned when there is no live OTP for the phone number
+	ErrOTPExpired = errors.New("OTP expired or not requested")
+
+	// ErrOTPAttemptsExceeded is returned when the OTP was guessed wrongly too many times
+	ErrOTPAttemptsExceeded = errors.New("too many incorrect OTP attempts")
+)
+
+// OTPThrottleError reports when the caller may request another OTP
+type OTPThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *OTPThrottleError) Error() string {
+	return fmt.Sprintf("%v, try again in %s", ErrOTPThrottled, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap lets errors.Is match ErrOTPThrottled
+func (e *OTPThrottleError) Unwrap() error {
+	return ErrOTPThrottled
+}
+
+// otpMessages are the SMS templates for each OTP purpose
+var otpMessages = map[string]string{
+	models.OTPPurposeLogin:          "%s is your Meesho login OTP. It expires in %d minutes. Do not share it with anyone.",
+	models.OTPPurposePhoneChangeOld: "%s is your OTP to move your Meesho account to a new number. It expires in %d minutes. Ignore this if you did not request it.",
+	models.OTPPurposePhoneChangeNew: "%s is your OTP to link this number to your Meesho account. It expires in %d minutes. Do not share it with anyone.",
+}
+
+// OTPRequestResult describes an OTP that was sent
+type OTPRequestResult struct {
+	ChallengeID string    `json:"challenge_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	ResendAfter time.Time `json:"resend_after"`
+}
+
+// OTPService issues and verifies one-time login codes
+type OTPService struct {
+	db          *gorm.DB
+	sms         SMSSender
+	secret      []byte
+	ttl         time.Duration
+	maxAttempts int
+
+	resendCooldown time.Duration
+	phoneLimit     int
+	phoneWindow    time.Duration
+	ipLimit        int
+	ipWindow       time.Duration
+	purgeInterval  time.Duration
+}
+
+// NewOTPService creates a new OTP service. OTP_SECRET must be set outside
+// development so that every instance hashes codes alike and a restart does
+// not void the codes in flight.
+func NewOTPService(sms SMSSender) (*OTPService, error) {
+	secret := []byte(os.Getenv("OTP_SECRET"))
+	if len(secret) == 0 {
+		if !configs.IsDevelopment() {
+			return nil, errors.New("OTP_SECRET must be set unless APP_ENV=development")
+		}
+		// A single local process can live with codes that die on restart
+		fmt.Printf("Warning: OTP_SECRET not set. Using a random key for this process.\n")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	return &OTPService{
+		db:             configs.DB,
+		sms:            sms,
+		secret:         secret,
+		ttl:            getEnvDuration("OTP_TTL", 5*time.Minute),
+		maxAttempts:    getEnvInt("OTP_MAX_ATTEMPTS", 5),
+		resendCooldown: getEnvDuration("OTP_RESEND_COOLDOWN", 30*time.Second),
+		phoneLimit:     getEnvInt("OTP_PHONE_LIMIT", 5),
+		phoneWindow:    getEnvDuration("OTP_PHONE_WINDOW", time.Hour),
+		ipLimit:        getEnvInt("OTP_IP_LIMIT", 20),
+		ipWindow:       getEnvDuration("OTP_IP_WINDOW", time.Hour),
+		purgeInterval:  getEnvDuration("OTP_PURGE_INTERVAL", time.Hour),
+	}, nil
+}
+
+// RequestOTP sends a new code for the purpose to the phone number.
+// Any earlier code for the same number and purpose stops working.
+func (s *OTPService) RequestOTP(purpose, phoneNumber, ip string) (*OTPRequestResult, error) {
+	template, ok := otpMessages[purpose]
+	if !ok {
+		return nil, fmt.Errorf("unknown OTP purpose %q", purpose)
+	}
+
+	now := time.Now()
+	phoneHash := *models.PhoneHashOf(phoneNumber)
+
+	if err := s.checkThrottle(purpose, phoneHash, ip, now); err != nil {
+		return nil, err
+	}
+
+	code, err := generateOTPCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OTP: %w", err)
+	}
+
+	challenge := models.OTPChallenge{
+		ChallengeID: generateChallengeID(),
+		PhoneHash:   phoneHash,
+		Purpose:     purpose,
+		CodeHash:    s.hashCode(purpose, phoneNumber, code),
+		RequestIP:   ip,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.OTPChallenge{}).
+			Where("phone_hash = ? AND purpose = ? AND consumed_at IS NULL", phoneHash, purpose).
+			Update("consumed_at", now).Error
+		if err != nil {
+			return fmt.Errorf("failed to supersede previous OTPs: %w", err)
+		}
+		if err := tx.Create(&challenge).Error; err != nil {
+			return fmt.Errorf("failed to store OTP: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf(template, code, int(s.ttl.Minutes()))
+	if err := s.sms.Send(phoneNumber, message); err != nil {
+		// Revoke the code so a later request is not blocked by a code the user never got
+		s.db.Model(&challenge).Update("consumed_at", time.Now())
+		return nil, fmt.Errorf("failed to send OTP: %w", err)
+	}
+
+	return &OTPRequestResult{
+		ChallengeID: challenge.ChallengeID,
+		ExpiresAt:   challenge.ExpiresAt,
+		ResendAfter: challenge.CreatedAt.Add(s.resendCooldown),
+	}, nil
+}
+
+// OTPCheck is a code submitted for the live OTP of a phone number and purpose
+type OTPCheck struct {
+	Purpose     string
+	PhoneNumber string
+	Code        string
+}
+
+// VerifyOTP checks a code against the live OTP for the phone number and purpose and consumes it on success
+func (s *OTPService) VerifyOTP(purpose, phoneNumber, code string) error {
+	return s.VerifyOTPs(OTPCheck{Purpose: purpose, PhoneNumber: phoneNumber, Code: code})
+}
+
+// VerifyOTPs checks every code and consumes them together, only once all of
+// them match. A wrong code is counted against its own OTP and leaves the
+// others live, so the user can retry without requesting new codes.
+func (s *OTPService) VerifyOTPs(checks ...OTPCheck) error {
+	var verifyErr error
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		challenges := make([]models.OTPChallenge, 0, len(checks))
+		for _, check := range checks {
+			challenge, err := s.checkCode(tx, check)
+			if err != nil {
+				if isOTPVerifyError(err) {
+					verifyErr = err
+					return nil
+				}
+				return err
+			}
+			challenges = append(challenges, *challenge)
+		}
+
+		now := time.Now()
+		for _, challenge := range challenges {
+			if err := tx.Model(&challenge).Update("consumed_at", now).Error; err != nil {
+				return fmt.Errorf("failed to consume OTP: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return verifyErr
+}
+
+// checkCode locks the live OTP for the check and matches the code against it.
+// A failed attempt is recorded on the OTP; the OTP is not consumed.
+func (s *OTPService) checkCode(tx *gorm.DB, check OTPCheck) (*models.OTPChallenge, error) {
+	var challenge models.OTPChallenge
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("phone_hash = ? AND purpose = ? AND consumed_at IS NULL AND expires_at > ?", *models.PhoneHashOf(check.PhoneNumber), check.Purpose, time.Now()).
+		Order("id DESC").
+		First(&challenge).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrOTPExpired
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	if challenge.Attempts >= s.maxAttempts {
+		return nil, ErrOTPAttemptsExceeded
+	}
+
+	expected := s.hashCode(check.Purpose, check.PhoneNumber, check.Code)
+	if !hmac.Equal([]byte(expected), []byte(challenge.CodeHash)) {
+		// The failed attempt is committed so guesses are counted
+		attempts := challenge.Attempts + 1
+		if err := tx.Model(&challenge).Update("attempts", attempts).Error; err != nil {
+			return nil, fmt.Errorf("failed to record OTP attempt: %w", err)
+		}
+		if attempts >= s.maxAttempts {
+			return nil, ErrOTPAttemptsExceeded
+		}
+		return nil, fmt.Errorf("%w, %d attempts left", ErrOTPInvalid, s.maxAttempts-attempts)
+	}
+
+	return &challenge, nil
+}
+
+// isOTPVerifyError reports whether err is a rejected code rather than a failure
+func isOTPVerifyError(err error) bool {
+	return errors.Is(err, ErrOTPInvalid) || errors.Is(err, ErrOTPExpired) || errors.Is(err, ErrOTPAttemptsExceeded)
+}
+
+// checkThrottle enforces the resend cooldown for the purpose and the per-phone
+// and per-IP send limits, which are shared by all purposes
+func (s *OTPService) checkThrottle(purpose, phoneHash, ip string, now time.Time) error {
+	var latest models.OTPChallenge
+	err := s.db.Where("phone_hash = ? AND purpose = ?", phoneHash, purpose).Order("id DESC").First(&latest).Error
+	if err == nil {
+		if wait := latest.CreatedAt.Add(s.resendCooldown).Sub(now); wait > 0 {
+			return &OTPThrottleError{RetryAfter: wait}
+		}
+	} else if err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	if err := s.checkWindow("phone_hash = ?", phoneHash, s.phoneLimit, s.phoneWindow, now); err != nil {
+		return err
+	}
+	if ip != "" {
+		if err := s.checkWindow("request_ip = ?", ip, s.ipLimit, s.ipWindow, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkWindow rejects the request if limit OTPs were already sent for the key within the window
+func (s *OTPService) checkWindow(condition, key string, limit int, window time.Duration, now time.Time) error {
+	var sent []models.OTPChallenge
+	err := s.db.Select("created_at").
+		Where(condition+" AND created_at > ?", key, now.Add(-window)).
+		Order("created_at").
+		Limit(limit).
+		Find(&sent).Error
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	if len(sent) < limit {
+		return nil
+	}
+
+	// The oldest send in the window has to age out before another is allowed
+	return &OTPThrottleError{RetryAfter: sent[0].CreatedAt.Add(window).Sub(now)}
+}
+
+// PurgeOTPs deletes OTPs that are too old to be verified or to count towards
+// any send limit and returns how many were deleted
+func (s *OTPService) PurgeOTPs() (int64, error) {
+	retention := s.ttl
+	for _, window := range []time.Duration{s.resendCooldown, s.phoneWindow, s.ipWindow} {
+		if window > retention {
+			retention = window
+		}
+	}
+
+	result := s.db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.OTPChallenge{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge OTPs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// StartOTPPurger periodically purges old OTPs until the context is cancelled
+func (s *OTPService) StartOTPPurger(ctx context.Context) {
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.PurgeOTPs(); err != nil {
+				fmt.Printf("Warning: OTP purge failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// hashCode returns a keyed hash of the code bound to the purpose and phone number
+func (s *OTPService) hashCode(purpose, phoneNumber, code string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + ":" + phoneNumber + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateOTPCode returns a uniformly random 6-digit code
+func generateOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// generateChallengeID generates a unique OTP challenge ID
+func generateChallengeID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return "otp_" + hex.EncodeToString(bytes)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"meesho-clone/internal/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func seedOTP(t *testing.T, db *gorm.DB, s *OTPService, purpose, phoneNumber, code string) models.OTPChallenge {
+	t.Helper()
+
+	challenge := models.OTPChallenge{
+		ChallengeID: generateChallengeID(),
+		PhoneHash:   *models.PhoneHashOf(phoneNumber),
+		Purpose:     purpose,
+		CodeHash:    s.hashCode(purpose, phoneNumber, code),
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	if err := db.Create(&challenge).Error; err != nil {
+		t.Fatalf("failed to seed OTP: %v", err)
+	}
+	return challenge
+}
+
+func TestVerifyOTPsConsumesNothingWhenOneCodeIsWrong(t *testing.T) {
+	db := openTestDB(t)
+	s := &OTPService{db: db, secret: []byte("test"), maxAttempts: 5}
+
+	old := seedOTP(t, db, s, models.OTPPurposePhoneChangeOld, "9000000001", "111111")
+	seedOTP(t, db, s, models.OTPPurposePhoneChangeNew, "9000000002", "222222")
+
+	err := s.VerifyOTPs(
+		OTPCheck{Purpose: models.OTPPurposePhoneChangeOld, PhoneNumber: "9000000001", Code: "111111"},
+		OTPCheck{Purpose: models.OTPPurposePhoneChangeNew, PhoneNumber: "9000000002", Code: "000000"},
+	)
+	if !errors.Is(err, ErrOTPInvalid) {
+		t.Fatalf("expected ErrOTPInvalid, got %v", err)
+	}
+
+	var reloaded models.OTPChallenge
+	db.First(&reloaded, old.ID)
+	if reloaded.ConsumedAt != nil {
+		t.Fatal("the old number's OTP was consumed although the new code was wrong")
+	}
+
+	err = s.VerifyOTPs(
+		OTPCheck{Purpose: models.OTPPurposePhoneChangeOld, PhoneNumber: "9000000001", Code: "111111"},
+		OTPCheck{Purpose: models.OTPPurposePhoneChangeNew, PhoneNumber: "9000000002", Code: "222222"},
+	)
+	if err != nil {
+		t.Fatalf("retry with both codes failed: %v", err)
+	}
+
+	var live int64
+	db.Model(&models.OTPChallenge{}).Where("consumed_at IS NULL").Count(&live)
+	if live != 0 {
+		t.Fatalf("expected both OTPs consumed, %d still live", live)
+	}
+}
+
+func TestPurgeOTPsKeepsRowsInsideTheThrottleWindow(t *testing.T) {
+	db := openTestDB(t)
+	s := &OTPService{db: db, secret: []byte("test"), ttl: 5 * time.Minute, phoneWindow: time.Hour, ipWindow: time.Hour}
+
+	recent := seedOTP(t, db, s, models.OTPPurposeLogin, "9000000001", "111111")
+	old := seedOTP(t, db, s, models.OTPPurposeLogin, "9000000002", "222222")
+	db.Model(&old).Update("created_at", time.Now().Add(-2*time.Hour))
+
+	purged, err := s.PurgeOTPs()
+	if err != nil {
+		t.Fatalf("PurgeOTPs failed: %v", err)
+	}
+	if purged != 1 {
+		t.Fatalf("expected 1 OTP purged, got %d", purged)
+	}
+
+	var remaining []models.OTPChallenge
+	db.Find(&remaining)
+	if len(remaining) != 1 || remaining[0].ID != recent.ID {
+		t.Fatalf("expected only the recent OTP to remain, got %+v", remaining)
+	}
+}
+
+func TestNewOTPServiceRequiresSecretOutsideDevelopment(t *testing.T) {
+	t.Setenv("OTP_SECRET", "")
+	t.Setenv("APP_ENV", "")
+	if _, err := NewOTPService(&ConsoleSMSSender{}); err == nil {
+		t.Fatal("NewOTPService without OTP_SECRET outside development should fail")
+	}
+
+	t.Setenv("APP_ENV", "development")
+	if _, err := NewOTPService(&ConsoleSMSSender{}); err != nil {
+		t.Fatalf("NewOTPService in development: %v", err)
+	}
+}
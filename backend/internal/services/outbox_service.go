@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxService manages outbox events and delivers them in the background
+type OutboxService struct {
+	db             *gorm.DB
+	rtoService     *RTOService
+	paymentService *PaymentService
+	pollInterval time.Duration
+	batchSize    int
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	leaseTime    time.Duration
+}
+
+// rtoDropPendingError is returned for a restore whose unit has not been
+// dropped yet; the restore waits until the drop is next attempted
+type rtoDropPendingError struct {
+	until time.Time
+}
+
+func (e *rtoDropPendingError) Error() string {
+	return "waiting for the RTO drop of the unit"
+}
+
+// rtoDropFailedError is returned for a restore whose unit was never dropped
+// because the drop failed permanently
+type rtoDropFailedError struct {
+	dropID uint
+}
+
+func (e *rtoDropFailedError) Error() string {
+	return fmt.Sprintf("RTO drop event %d failed, the unit was never dropped", e.dropID)
+}
+
+// NewOutboxService creates a new outbox service
+func NewOutboxService() *OutboxService {
+	return &OutboxService{
+		db:             configs.DB,
+		rtoService:     NewRTOService(),
+		paymentService: NewPaymentService(),
+		pollInterval:   getEnvDuration("OUTBOX_POLL_INTERVAL", 5*time.Second),
+		batchSize:      getEnvInt("OUTBOX_BATCH_SIZE", 20),
+		maxAttempts:    getEnvInt("OUTBOX_MAX_ATTEMPTS", 8),
+		baseBackoff:    getEnvDuration("OUTBOX_BASE_BACKOFF", 2*time.Second),
+		maxBackoff:     getEnvDuration("OUTBOX_MAX_BACKOFF", 10*time.Minute),
+		leaseTime:      time.Minute,
+	}
+}
+
+// NewRTODropEvent builds the outbox event that drops an ordered unit from the RTO list of its code
+func NewRTODropEvent(orderID string, item models.OrderItem) (models.OutboxEvent, error) {
+	return newRTOEvent(models.OutboxEventRTODrop, orderID, item)
+}
+
+// NewRTORestoreEvent builds the outbox event that puts a cancelled unit back into the RTO list of its code
+func NewRTORestoreEvent(orderID string, item models.OrderItem) (models.OutboxEvent, error) {
+	return newRTOEvent(models.OutboxEventRTORestore, orderID, item)
+}
+
+// PaymentRefundRequest names the payment a refund event returns
+type PaymentRefundRequest struct {
+	PaymentID string `json:"payment_id"`
+}
+
+// NewPaymentRefundEvent builds the outbox event that refunds a completed payment
+func NewPaymentRefundEvent(payment models.Payment) (models.OutboxEvent, error) {
+	payload, err := json.Marshal(PaymentRefundRequest{PaymentID: payment.PaymentID})
+	if err != nil {
+		return models.OutboxEvent{}, fmt.Errorf("failed to marshal refund payload: %w", err)
+	}
+
+	return models.OutboxEvent{
+		OrderID:       payment.OrderID,
+		EventType:     models.OutboxEventPaymentRefund,
+		Payload:       string(payload),
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+// newRTOEvent builds an outbox event carrying an RTO request for one order item
+func newRTOEvent(eventType, orderID string, item models.OrderItem) (models.OutboxEvent, error) {
+	payload, err := json.Marshal(RTODropRequest{
+		Code:        item.RTOCode,
+		ProductID:   ParseRTOProductID(item.ProductID),
+		CatalogID:   ParseRTOCatalogID(item.CatalogID),
+		SubOrderNum: item.SubOrderNum,
+		OrderDate:   item.RTOOrderDate,
+	})
+	if err != nil {
+		return models.OutboxEvent{}, fmt.Errorf("failed to marshal RTO payload: %w", err)
+	}
+
+	return models.OutboxEvent{
+		OrderID:       orderID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	}, nil
+}
+
+// Start polls for due events until the context is cancelled
+func (s *OutboxService) Start(ctx context.Context) {
+	fmt.Printf("Outbox dispatcher started (poll interval %s)\n", s.pollInterval)
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.DispatchDue(); err != nil {
+			fmt.Printf("Warning: Outbox dispatch failed: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("Outbox dispatcher stopped\n")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue delivers one batch of due events and returns how many were attempted
+func (s *OutboxService) DispatchDue() (int, error) {
+	events, err := s.claimDueEvents()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		s.deliver(event)
+	}
+
+	return len(events), nil
+}
+
+// claimDueEvents locks a batch of due events and leases them to this dispatcher
+func (s *OutboxService) claimDueEvents() ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(s.batchSize).
+			Find(&events).Error
+		if err != nil {
+			return fmt.Errorf("failed to query due outbox events: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+
+		// Push the next attempt out so other dispatchers skip these while we work
+		err = tx.Model(&models.OutboxEvent{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(s.leaseTime)).Error
+		if err != nil {
+			return fmt.Errorf("failed to lease outbox events: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// deliver performs a single delivery attempt and records the outcome
+func (s *OutboxService) deliver(event models.OutboxEvent) {
+	deliveryErr := s.handle(event)
+
+	var pending *rtoDropPendingError
+	if errors.As(deliveryErr, &pending) {
+		// Waiting is not a failed attempt
+		err := s.db.Model(&models.OutboxEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+			"next_attempt_at": pending.until,
+			"last_error":      deliveryErr.Error(),
+		}).Error
+		if err != nil {
+			fmt.Printf("Warning: Failed to reschedule outbox event %d: %v\n", event.ID, err)
+		}
+		return
+	}
+
+	var dropFailed *rtoDropFailedError
+	if errors.As(deliveryErr, &dropFailed) {
+		s.skipRestore(event, dropFailed)
+		return
+	}
+
+	attempts := event.Attempts + 1
+
+	updates := map[string]interface{}{
+		"attempts": attempts,
+	}
+
+	if deliveryErr == nil {
+		now := time.Now()
+		updates["status"] = models.OutboxStatusDelivered
+		updates["delivered_at"] = &now
+		updates["last_error"] = ""
+		fmt.Printf("Outbox event %d (%s) for order %s delivered\n", event.ID, event.EventType, event.OrderID)
+	} else {
+		updates["last_error"] = deliveryErr.Error()
+		if attempts >= s.maxAttempts {
+			updates["status"] = models.OutboxStatusFailed
+			fmt.Printf("Outbox event %d (%s) for order %s failed permanently: %v\n", event.ID, event.EventType, event.OrderID, deliveryErr)
+		} else {
+			updates["next_attempt_at"] = time.Now().Add(s.backoff(attempts))
+			fmt.Printf("Outbox event %d (%s) for order %s failed (attempt %d): %v\n", event.ID, event.EventType, event.OrderID, attempts, deliveryErr)
+		}
+	}
+
+	if err := s.db.Model(&models.OutboxEvent{}).Where("id = ?", event.ID).Updates(updates).Error; err != nil {
+		fmt.Printf("Warning: Failed to record outbox delivery for event %d: %v\n", event.ID, err)
+		return
+	}
+
+	if err := s.refreshOrderRTODropStatus(event.OrderID); err != nil {
+		fmt.Printf("Warning: Failed to update RTO drop status for order %s: %v\n", event.OrderID, err)
+	}
+}
+
+// handle delivers an event to its destination
+func (s *OutboxService) handle(event models.OutboxEvent) error {
+	switch event.EventType {
+	case models.OutboxEventRTODrop:
+		var dropRequest RTODropRequest
+		if err := json.Unmarshal([]byte(event.Payload), &dropRequest); err != nil {
+			return fmt.Errorf("invalid RTO drop payload: %w", err)
+		}
+		return s.rtoService.DropProduct(dropRequest)
+	case models.OutboxEventRTORestore:
+		var restoreRequest RTODropRequest
+		if err := json.Unmarshal([]byte(event.Payload), &restoreRequest); err != nil {
+			return fmt.Errorf("invalid RTO restore payload: %w", err)
+		}
+		// Restoring before the drop has landed would let the drop remove the
+		// unit again. The drop of the same unit carries the same payload.
+		var drop models.OutboxEvent
+		result := s.db.Where("order_id = ? AND event_type = ? AND payload = ?", event.OrderID, models.OutboxEventRTODrop, event.Payload).
+			Order("id DESC").Limit(1).Find(&drop)
+		if result.Error != nil {
+			return fmt.Errorf("database error: %v", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			switch drop.Status {
+			case models.OutboxStatusPending:
+				return &rtoDropPendingError{until: drop.NextAttemptAt.Add(s.pollInterval)}
+			case models.OutboxStatusFailed:
+				return &rtoDropFailedError{dropID: drop.ID}
+			}
+		}
+		return s.rtoService.RestoreProduct(restoreRequest)
+	case models.OutboxEventPaymentRefund:
+		var refundRequest PaymentRefundRequest
+		if err := json.Unmarshal([]byte(event.Payload), &refundRequest); err != nil {
+			return fmt.Errorf("invalid payment refund payload: %w", err)
+		}
+		return s.paymentService.RefundPayment(refundRequest.PaymentID)
+	default:
+		return fmt.Errorf("unknown outbox event type %q", event.EventType)
+	}
+}
+
+// skipRestore gives up on a unit whose drop failed permanently: the unit is
+// still listed, so there is nothing to restore, and the drop must not be
+// retried for a cancelled order
+func (s *OutboxService) skipRestore(event models.OutboxEvent, dropFailed *rtoDropFailedError) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.OutboxEvent{}).
+			Where("id = ? AND status = ?", dropFailed.dropID, models.OutboxStatusFailed).
+			Updates(map[string]interface{}{
+				"status":     models.OutboxStatusSkipped,
+				"last_error": fmt.Sprintf("order cancelled, restore event %d skipped", event.ID),
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.OutboxEvent{}).Where("id = ?", event.ID).Updates(map[string]interface{}{
+			"status":     models.OutboxStatusSkipped,
+			"last_error": dropFailed.Error(),
+		}).Error
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to skip outbox event %d: %v\n", event.ID, err)
+		return
+	}
+	fmt.Printf("Outbox event %d (%s) for order %s skipped: %v\n", event.ID, event.EventType, event.OrderID, dropFailed)
+
+	if err := s.refreshOrderRTODropStatus(event.OrderID); err != nil {
+		fmt.Printf("Warning: Failed to update RTO drop status for order %s: %v\n", event.OrderID, err)
+	}
+}
+
+// backoff returns the exponential delay before the given attempt is retried
+func (s *OutboxService) backoff(attempts int) time.Duration {
+	delay := float64(s.baseBackoff) * math.Pow(2, float64(attempts-1))
+	if delay > float64(s.maxBackoff) {
+		return s.maxBackoff
+	}
+	return time.Duration(delay)
+}
+
+// refreshOrderRTODropStatus rolls the status of an order's RTO drop events up onto the order
+func (s *OutboxService) refreshOrderRTODropStatus(orderID string) error {
+	if orderID == "" {
+		return nil
+	}
+
+	var events []models.OutboxEvent
+	err := s.db.Where("order_id = ? AND event_type = ?", orderID, models.OutboxEventRTODrop).Find(&events).Error
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	status := models.OutboxStatusDelivered
+	for _, event := range events {
+		if event.Status == models.OutboxStatusFailed {
+			status = models.OutboxStatusFailed
+			break
+		}
+		if event.Status == models.OutboxStatusPending {
+			status = models.OutboxStatusPending
+		}
+		if event.Status == models.OutboxStatusSkipped && status == models.OutboxStatusDelivered {
+			status = models.OutboxStatusSkipped
+		}
+	}
+
+	return s.db.Model(&models.Order{}).Where("order_id = ?", orderID).Update("rto_drop_status", status).Error
+}
+
+// ListEvents returns outbox events filtered by status (pending and failed when empty)
+func (s *OutboxService) ListEvents(status string, limit int) ([]models.OutboxEvent, error) {
+	query := s.db.Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status IN ?", []string{models.OutboxStatusPending, models.OutboxStatusFailed})
+	}
+
+	var events []models.OutboxEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return events, nil
+}
+
+// GetEventsByOrderID returns all outbox events recorded for an order
+func (s *OutboxService) GetEventsByOrderID(orderID string) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	if err := s.db.Where("order_id = ?", orderID).Order("id").Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return events, nil
+}
+
+// RetryEvent puts a failed event back into the queue for immediate delivery
+func (s *OutboxService) RetryEvent(id uint) error {
+	result := s.db.Model(&models.OutboxEvent{}).
+		Where("id = ? AND status = ?", id, models.OutboxStatusFailed).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("database error: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no failed outbox event with id %d", id)
+	}
+
+	var event models.OutboxEvent
+	if err := s.db.First(&event, id).Error; err == nil {
+		if err := s.refreshOrderRTODropStatus(event.OrderID); err != nil {
+			fmt.Printf("Warning: Failed to update RTO drop status for order %s: %v\n", event.OrderID, err)
+		}
+	}
+	return nil
+}
+
+// getEnvDuration reads a duration from the environment with a fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		fmt.Printf("Warning: Invalid %s %q. Using %s.\n", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// getEnvInt reads a positive integer from the environment with a fallback
+func getEnvInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		fmt.Printf("Warning: Invalid %s %q. Using %d.\n", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
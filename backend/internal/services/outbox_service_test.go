@@ -0,0 +1,68 @@
+package services
+
+import (
+	"meesho-clone/internal/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// seedDropAndRestore writes the drop and restore events of one cancelled unit
+func seedDropAndRestore(t *testing.T, db *gorm.DB, dropStatus string) (drop, restore models.OutboxEvent) {
+	t.Helper()
+
+	item := models.OrderItem{RTOCode: "C1", ProductID: "101", CatalogID: "900", SubOrderNum: "SO-1", RTOOrderDate: "2025-01-01"}
+	drop, err := NewRTODropEvent("ORDER_1", item)
+	if err != nil {
+		t.Fatalf("NewRTODropEvent: %v", err)
+	}
+	drop.Status = dropStatus
+	drop.NextAttemptAt = time.Now().Add(time.Hour).Truncate(time.Second)
+	restore, err = NewRTORestoreEvent("ORDER_1", item)
+	if err != nil {
+		t.Fatalf("NewRTORestoreEvent: %v", err)
+	}
+	if err := db.Create(&drop).Error; err != nil {
+		t.Fatalf("failed to seed drop event: %v", err)
+	}
+	if err := db.Create(&restore).Error; err != nil {
+		t.Fatalf("failed to seed restore event: %v", err)
+	}
+	return drop, restore
+}
+
+func TestRestoreWaitsForPendingDropWithoutUsingAttempts(t *testing.T) {
+	db := openTestDB(t)
+	service := NewOutboxService()
+	drop, restore := seedDropAndRestore(t, db, models.OutboxStatusPending)
+
+	for i := 0; i < 3; i++ {
+		service.deliver(restore)
+		db.First(&restore, restore.ID)
+	}
+
+	if restore.Attempts != 0 || restore.Status != models.OutboxStatusPending {
+		t.Fatalf("waiting restore has %d attempts and status %s, want 0 and pending", restore.Attempts, restore.Status)
+	}
+	if restore.NextAttemptAt.Before(drop.NextAttemptAt) {
+		t.Fatalf("restore rescheduled for %s, before its drop at %s", restore.NextAttemptAt, drop.NextAttemptAt)
+	}
+}
+
+func TestRestoreIsSkippedWhenDropFailed(t *testing.T) {
+	db := openTestDB(t)
+	service := NewOutboxService()
+	drop, restore := seedDropAndRestore(t, db, models.OutboxStatusFailed)
+
+	service.deliver(restore)
+
+	db.First(&drop, drop.ID)
+	db.First(&restore, restore.ID)
+	if drop.Status != models.OutboxStatusSkipped || restore.Status != models.OutboxStatusSkipped {
+		t.Fatalf("drop is %s and restore is %s, want both skipped", drop.Status, restore.Status)
+	}
+	if err := service.RetryEvent(drop.ID); err == nil {
+		t.Fatal("the skipped drop of a cancelled unit could be retried")
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+)
+
+// ErrWebhookNotSupported is returned by providers that never send webhooks
+var ErrWebhookNotSupported = errors.New("payment provider does not support webhooks")
+
+// ErrInvalidWebhookSignature is returned when a webhook signature does not verify
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// PaymentIntentRequest describes the payment to collect for an order
+type PaymentIntentRequest struct {
+	PaymentID string
+	OrderID   string
+	UserID    string
+	Amount    float64
+	Currency  string
+}
+
+// PaymentIntent is a provider's view of a payment
+type PaymentIntent struct {
+	ProviderRef string
+	Status      string
+	NextAction  string
+}
+
+// PaymentRefund is a provider's view of a refund
+type PaymentRefund struct {
+	RefundRef string
+	Amount    float64
+}
+
+// PaymentWebhookEvent is a verified status update sent by a provider
+type PaymentWebhookEvent struct {
+	EventID       string `json:"event_id"`
+	ProviderRef   string `json:"provider_ref"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// PaymentProvider is implemented by every way of collecting money for an order
+type PaymentProvider interface {
+	// Name identifies the provider in stored payments and webhook URLs
+	Name() string
+
+	// CreateIntent registers a payment with the provider
+	CreateIntent(req PaymentIntentRequest) (*PaymentIntent, error)
+
+	// Confirm checks with the provider whether the customer completed the
+	// payment and returns its status there. It must never report a payment
+	// as succeeded that the provider has not seen succeed.
+	Confirm(providerRef string) (*PaymentIntent, error)
+
+	// Status returns the provider's current status of a payment without
+	// acting on it
+	Status(providerRef string) (*PaymentIntent, error)
+
+	// Refund returns money for a completed payment
+	Refund(providerRef string, amount float64) (*PaymentRefund, error)
+
+	// VerifyWebhook checks the signature of a callback and parses it
+	VerifyWebhook(payload []byte, signature string) (*PaymentWebhookEvent, error)
+}
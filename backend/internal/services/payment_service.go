@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// paymentActor is recorded in the order history for payment-driven transitions
+const paymentActor = "system:payments"
+
+var (
+	// ErrUnknownPaymentMethod is returned for payment methods we do not offer
+	ErrUnknownPaymentMethod = errors.New("unknown payment method")
+
+	// ErrUnknownPaymentProvider is returned when a webhook names a provider we do not use
+	ErrUnknownPaymentProvider = errors.New("unknown payment provider")
+
+	// ErrPaymentNotFound is returned when no payment matches the request
+	ErrPaymentNotFound = errors.New("payment not found")
+
+	// ErrPaymentNotConfirmable is returned when a customer tries to confirm a
+	// payment that is only completed by us, such as cash on delivery
+	ErrPaymentNotConfirmable = errors.New("payment cannot be confirmed by the customer")
+)
+
+// paymentGatewayNone turns online payments off, leaving cash on delivery
+const paymentGatewayNone = "none"
+
+// PaymentService collects payments for orders through pluggable providers
+type PaymentService struct {
+	db             *gorm.DB
+	orderService   *OrderService
+	providers      map[string]PaymentProvider
+	onlineGateway  string
+	paymentTimeout time.Duration
+	sweepInterval  time.Duration
+}
+
+// NewPaymentService creates a new payment service. A misconfigured online
+// gateway turns online payments off; CheckPaymentConfig reports why at startup.
+func NewPaymentService() *PaymentService {
+	service := &PaymentService{
+		db:             configs.DB,
+		orderService:   NewOrderService(),
+		providers:      map[string]PaymentProvider{},
+		paymentTimeout: getEnvDuration("PAYMENT_TIMEOUT", 30*time.Minute),
+		sweepInterval:  getEnvDuration("PAYMENT_TIMEOUT_SWEEP_INTERVAL", time.Minute),
+	}
+
+	service.RegisterProvider(NewCODPaymentProvider())
+
+	gateway, err := newOnlineGateway()
+	if err != nil {
+		fmt.Printf("Warning: Online payments disabled: %v\n", err)
+	} else if gateway != nil {
+		service.RegisterProvider(gateway)
+		service.onlineGateway = gateway.Name()
+	}
+
+	return service
+}
+
+// CheckPaymentConfig reports whether PAYMENT_GATEWAY names a usable online
+// gateway. It is checked at startup so a missing gateway or secret stops the
+// server instead of silently falling back.
+func CheckPaymentConfig() error {
+	_, err := newOnlineGateway()
+	return err
+}
+
+// newOnlineGateway returns the provider named by PAYMENT_GATEWAY, or nil for
+// "none". The fake gateway is only available when APP_ENV is development.
+func newOnlineGateway() (PaymentProvider, error) {
+	switch name := os.Getenv("PAYMENT_GATEWAY"); name {
+	case "":
+		return nil, fmt.Errorf("PAYMENT_GATEWAY is not set, use %q to offer cash on delivery only", paymentGatewayNone)
+	case paymentGatewayNone:
+		return nil, nil
+	case "fake_gateway":
+		if !configs.IsDevelopment() {
+			return nil, errors.New("the fake payment gateway is only available with APP_ENV=development")
+		}
+		return NewFakePaymentProvider()
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaymentProvider, name)
+	}
+}
+
+// RegisterProvider makes a provider available for payments and webhooks
+func (s *PaymentService) RegisterProvider(provider PaymentProvider) {
+	s.providers[provider.Name()] = provider
+}
+
+// generatePaymentID generates a unique payment ID
+func generatePaymentID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return "pay_" + hex.EncodeToString(bytes)
+}
+
+// providerForMethod returns the provider that handles a payment method
+func (s *PaymentService) providerForMethod(method string) (PaymentProvider, error) {
+	name := ""
+	switch method {
+	case models.PaymentMethodCOD:
+		name = "cod"
+	case models.PaymentMethodOnline:
+		if s.onlineGateway == "" {
+			return nil, fmt.Errorf("%w: online payments are not available", ErrUnknownPaymentMethod)
+		}
+		name = s.onlineGateway
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaymentMethod, method)
+	}
+
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaymentProvider, name)
+	}
+	return provider, nil
+}
+
+// StartPayment creates the payment for a freshly placed order and moves the
+// order to CONFIRMED (cash on delivery) or PENDING_PAYMENT (online).
+// If the payment cannot be started the order is cancelled.
+func (s *PaymentService) StartPayment(order *models.Order, method string) (*models.Payment, *models.Order, error) {
+	payment, updated, err := s.startPayment(order, method)
+	if err != nil {
+		if _, cancelErr := s.orderService.CancelOrderAsSystem(order.OrderID, paymentActor, "payment could not be started"); cancelErr != nil {
+			fmt.Printf("Warning: Failed to cancel order %s after payment error: %v\n", order.OrderID, cancelErr)
+		}
+		return nil, nil, err
+	}
+	return payment, updated, nil
+}
+
+// startPayment creates the payment intent and moves the order forward
+func (s *PaymentService) startPayment(order *models.Order, method string) (*models.Payment, *models.Order, error) {
+	provider, err := s.providerForMethod(method)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payment := models.Payment{
+		PaymentID: generatePaymentID(),
+		OrderID:   order.OrderID,
+		UserID:    order.UserID,
+		Method:    method,
+		Provider:  provider.Name(),
+		Amount:    order.TotalAmount,
+		Currency:  "INR",
+		Status:    models.PaymentStatusPending,
+	}
+
+	intent, err := provider.CreateIntent(PaymentIntentRequest{
+		PaymentID: payment.PaymentID,
+		OrderID:   order.OrderID,
+		UserID:    order.UserID,
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create payment intent: %w", err)
+	}
+	payment.ProviderRef = intent.ProviderRef
+	payment.NextAction = intent.NextAction
+
+	if err := s.db.Create(&payment).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	nextStatus := models.OrderStatusPendingPayment
+	if method == models.PaymentMethodCOD {
+		nextStatus = models.OrderStatusConfirmed
+	}
+
+	updated, err := s.orderService.TransitionStatus(order.OrderID, nextStatus, paymentActor, "payment method "+method)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &payment, updated, nil
+}
+
+// ConfirmPayment completes a pending online payment for its owner once the
+// provider reports it as paid
+func (s *PaymentService) ConfirmPayment(paymentID, userID string) (*models.Payment, error) {
+	var payment models.Payment
+	err := s.db.Where("payment_id = ? AND user_id = ?", paymentID, userID).First(&payment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if payment.Method != models.PaymentMethodOnline {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentNotConfirmable, payment.Method)
+	}
+
+	return s.confirm(&payment)
+}
+
+// confirm asks the payment's provider for the outcome and applies it
+func (s *PaymentService) confirm(payment *models.Payment) (*models.Payment, error) {
+	provider, ok := s.providers[payment.Provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaymentProvider, payment.Provider)
+	}
+
+	intent, err := provider.Confirm(payment.ProviderRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm payment: %w", err)
+	}
+	if intent.ProviderRef != payment.ProviderRef {
+		return nil, fmt.Errorf("provider confirmed %s instead of %s", intent.ProviderRef, payment.ProviderRef)
+	}
+
+	return s.applyStatus(payment, intent.Status, "")
+}
+
+// HandleWebhook verifies a provider callback and applies the status it reports
+func (s *PaymentService) HandleWebhook(providerName string, payload []byte, signature string) (*models.Payment, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaymentProvider, providerName)
+	}
+
+	event, err := provider.VerifyWebhook(payload, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var payment models.Payment
+	err = s.db.Where("provider = ? AND provider_ref = ?", providerName, event.ProviderRef).First(&payment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	fmt.Printf("Payment webhook %s from %s: %s -> %s\n", event.EventID, providerName, payment.PaymentID, event.Status)
+	return s.applyStatus(&payment, event.Status, event.FailureReason)
+}
+
+// applyStatus moves a pending payment to a final status and moves its order
+// along in the same transaction. Payments that already left pending are
+// returned unchanged, so repeated webhooks are harmless. A success that
+// arrives after the payment was failed by a cancellation or timeout is still
+// recorded, and the captured money is queued for a refund.
+func (s *PaymentService) applyStatus(payment *models.Payment, status, failureReason string) (*models.Payment, error) {
+	if status != models.PaymentStatusSucceeded && status != models.PaymentStatusFailed {
+		return payment, nil
+	}
+
+	fromStatuses := []string{models.PaymentStatusPending}
+	if status == models.PaymentStatusSucceeded {
+		fromStatuses = append(fromStatuses, models.PaymentStatusFailed)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Payment{}).
+			Where("id = ? AND status IN ?", payment.ID, fromStatuses).
+			Updates(map[string]interface{}{
+				"status":         status,
+				"failure_reason": failureReason,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update payment: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		var order models.Order
+		if err := tx.Where("order_id = ?", payment.OrderID).First(&order).Error; err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+
+		switch order.Status {
+		case models.OrderStatusPendingPayment:
+			var err error
+			if status == models.PaymentStatusSucceeded {
+				_, err = s.orderService.transition(tx, order.OrderID, models.OrderStatusConfirmed, paymentActor, "payment succeeded")
+			} else {
+				_, err = s.orderService.cancel(tx, order.OrderID, paymentActor, "payment failed")
+			}
+			return err
+		case models.OrderStatusCancelled, models.OrderStatusReturned:
+			if status != models.PaymentStatusSucceeded {
+				return nil
+			}
+			fmt.Printf("Payment %s succeeded after order %s was %s, queueing a refund\n", payment.PaymentID, order.OrderID, order.Status)
+			return queueRefunds(tx, order.OrderID, "order "+order.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.reload(payment)
+}
+
+// StartPaymentTimeoutSweeper periodically cancels orders whose online
+// payment was not completed in time until the context is cancelled
+func (s *PaymentService) StartPaymentTimeoutSweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cancelled, err := s.ExpireUnpaidOrders()
+			if err != nil {
+				fmt.Printf("Warning: Payment timeout sweep failed: %v\n", err)
+			} else if cancelled > 0 {
+				fmt.Printf("Cancelled %d orders with unpaid online payments\n", cancelled)
+			}
+		}
+	}
+}
+
+// ExpireUnpaidOrders cancels orders left in PENDING_PAYMENT for longer than
+// the payment timeout. Their units go back to the RTO list through the usual
+// restore events. A payment the provider reports as paid is applied instead.
+func (s *PaymentService) ExpireUnpaidOrders() (int, error) {
+	var payments []models.Payment
+	err := s.db.Joins("JOIN orders ON orders.order_id = payments.order_id").
+		Where("payments.status = ? AND orders.status = ? AND payments.created_at < ?",
+			models.PaymentStatusPending, models.OrderStatusPendingPayment, time.Now().Add(-s.paymentTimeout)).
+		Find(&payments).Error
+	if err != nil {
+		return 0, fmt.Errorf("database error: %v", err)
+	}
+
+	cancelled := 0
+	for i := range payments {
+		payment := &payments[i]
+
+		status, failureReason := models.PaymentStatusFailed, "payment timed out"
+		if provider, ok := s.providers[payment.Provider]; ok {
+			intent, err := provider.Status(payment.ProviderRef)
+			if err != nil {
+				fmt.Printf("Warning: Failed to check payment %s before timing it out: %v\n", payment.PaymentID, err)
+				continue
+			}
+			if intent.Status == models.PaymentStatusSucceeded {
+				status, failureReason = models.PaymentStatusSucceeded, ""
+			}
+		}
+
+		updated, err := s.applyStatus(payment, status, failureReason)
+		if err != nil {
+			fmt.Printf("Warning: Failed to time out payment %s: %v\n", payment.PaymentID, err)
+			continue
+		}
+		if updated.Status == models.PaymentStatusFailed {
+			cancelled++
+		}
+	}
+	return cancelled, nil
+}
+
+// CollectCashOnDelivery marks the pending cash-on-delivery payment of a delivered order as collected
+func (s *PaymentService) CollectCashOnDelivery(orderID string) error {
+	var payments []models.Payment
+	err := s.db.Where("order_id = ? AND method = ? AND status = ?", orderID, models.PaymentMethodCOD, models.PaymentStatusPending).Find(&payments).Error
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	for i := range payments {
+		if _, err := s.confirm(&payments[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueRefunds voids the payments of a cancelled or returned order that were
+// never completed and queues a refund event for each completed one, in the
+// transaction that cancels or returns the order
+func queueRefunds(tx *gorm.DB, orderID, reason string) error {
+	err := tx.Model(&models.Payment{}).
+		Where("order_id = ? AND status = ?", orderID, models.PaymentStatusPending).
+		Updates(map[string]interface{}{
+			"status":         models.PaymentStatusFailed,
+			"failure_reason": reason,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to void pending payments: %w", err)
+	}
+
+	var payments []models.Payment
+	err = tx.Where("order_id = ? AND status = ?", orderID, models.PaymentStatusSucceeded).Find(&payments).Error
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	events := make([]models.OutboxEvent, 0, len(payments))
+	for _, payment := range payments {
+		event, err := NewPaymentRefundEvent(payment)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	if len(events) > 0 {
+		if err := tx.Create(&events).Error; err != nil {
+			return fmt.Errorf("failed to create outbox events: %w", err)
+		}
+	}
+	return nil
+}
+
+// RefundPayment returns the money of a completed payment through its
+// provider. It is delivered by the outbox, so a payment that was already
+// refunded is left alone.
+func (s *PaymentService) RefundPayment(paymentID string) error {
+	var payment models.Payment
+	if err := s.db.Where("payment_id = ?", paymentID).First(&payment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrPaymentNotFound
+		}
+		return fmt.Errorf("database error: %v", err)
+	}
+	if payment.Status != models.PaymentStatusSucceeded {
+		return nil
+	}
+
+	provider, ok := s.providers[payment.Provider]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPaymentProvider, payment.Provider)
+	}
+
+	refund, err := provider.Refund(payment.ProviderRef, payment.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to refund payment %s: %w", payment.PaymentID, err)
+	}
+
+	err = s.db.Model(&models.Payment{}).
+		Where("id = ? AND status = ?", payment.ID, models.PaymentStatusSucceeded).
+		Updates(map[string]interface{}{
+			"status":     models.PaymentStatusRefunded,
+			"refund_ref": refund.RefundRef,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to record refund for payment %s: %w", payment.PaymentID, err)
+	}
+	return nil
+}
+
+// GetPaymentsByOrderID returns the payments made for an order
+func (s *PaymentService) GetPaymentsByOrderID(orderID string) ([]models.Payment, error) {
+	var payments []models.Payment
+	if err := s.db.Where("order_id = ?", orderID).Order("id").Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return payments, nil
+}
+
+// reload re-reads a payment after it was updated
+func (s *PaymentService) reload(payment *models.Payment) (*models.Payment, error) {
+	var fresh models.Payment
+	if err := s.db.First(&fresh, payment.ID).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return &fresh, nil
+}
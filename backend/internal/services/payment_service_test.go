@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"testing"
+	"time"
+)
+
+func TestCheckPaymentConfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		gateway string
+		appEnv  string
+		secret  string
+		wantErr bool
+	}{
+		{name: "unset", wantErr: true},
+		{name: "cash on delivery only", gateway: "none"},
+		{name: "unknown gateway", gateway: "acme_pay", wantErr: true},
+		{name: "fake gateway outside development", gateway: "fake_gateway", secret: "s3cret", wantErr: true},
+		{name: "fake gateway without secret", gateway: "fake_gateway", appEnv: "development", wantErr: true},
+		{name: "fake gateway in development", gateway: "fake_gateway", appEnv: "development", secret: "s3cret"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("PAYMENT_GATEWAY", tc.gateway)
+			t.Setenv("APP_ENV", tc.appEnv)
+			t.Setenv("FAKE_GATEWAY_WEBHOOK_SECRET", tc.secret)
+
+			err := CheckPaymentConfig()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CheckPaymentConfig() = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomerCannotConfirmCashOnDelivery(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("PAYMENT_GATEWAY", "none")
+
+	payment := models.Payment{
+		PaymentID: "pay_cod",
+		OrderID:   "ORDER_1",
+		UserID:    "buyer",
+		Method:    models.PaymentMethodCOD,
+		Provider:  "cod",
+		Status:    models.PaymentStatusPending,
+	}
+	if err := db.Create(&payment).Error; err != nil {
+		t.Fatalf("failed to seed payment: %v", err)
+	}
+
+	_, err := NewPaymentService().ConfirmPayment("pay_cod", "buyer")
+	if !errors.Is(err, ErrPaymentNotConfirmable) {
+		t.Fatalf("ConfirmPayment on cash on delivery returned %v, want %v", err, ErrPaymentNotConfirmable)
+	}
+}
+
+func TestUnpaidOrdersAreCancelledAfterTimeout(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("RTO_STORE", "db")
+	t.Setenv("PAYMENT_GATEWAY", "fake_gateway")
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("FAKE_GATEWAY_WEBHOOK_SECRET", "s3cret")
+	seedRTOList(t, db, "C1", 101, 1, "SO-1")
+
+	order, err := NewOrderService().CreateOrder(CreateOrderInput{
+		OrderID: "ORDER_1",
+		UserID:  "buyer",
+		Items:   []models.OrderItem{{RTOCode: "C1", ProductID: "101", CatalogID: "900", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	service := NewPaymentService()
+	payment, _, err := service.StartPayment(order, models.PaymentMethodOnline)
+	if err != nil {
+		t.Fatalf("StartPayment: %v", err)
+	}
+	db.Model(&models.Payment{}).Where("id = ?", payment.ID).Update("created_at", time.Now().Add(-time.Hour))
+
+	cancelled, err := service.ExpireUnpaidOrders()
+	if err != nil || cancelled != 1 {
+		t.Fatalf("ExpireUnpaidOrders() = %d, %v, want 1 cancelled order", cancelled, err)
+	}
+
+	var restores int64
+	db.Model(&models.OutboxEvent{}).Where("order_id = ? AND event_type = ?", "ORDER_1", models.OutboxEventRTORestore).Count(&restores)
+	db.Where("order_id = ?", "ORDER_1").First(order)
+	if order.Status != models.OrderStatusCancelled || restores != 1 {
+		t.Fatalf("order is %s with %d restore events, want cancelled with 1", order.Status, restores)
+	}
+}
+
+func TestConfirmingAnOrderIssuesItsInvoice(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("RTO_STORE", "db")
+	t.Setenv("PAYMENT_GATEWAY", "none")
+	seedRTOList(t, db, "C1", 101, 1, "SO-1")
+
+	order, err := NewOrderService().CreateOrder(CreateOrderInput{
+		OrderID: "ORDER_1",
+		UserID:  "buyer",
+		Items:   []models.OrderItem{{RTOCode: "C1", ProductID: "101", CatalogID: "900", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	invoices := NewInvoiceService()
+	if _, err := invoices.GetInvoice("ORDER_1"); !errors.Is(err, ErrInvoiceNotAvailable) {
+		t.Fatalf("GetInvoice before confirmation returned %v, want %v", err, ErrInvoiceNotAvailable)
+	}
+
+	if _, _, err := NewPaymentService().StartPayment(order, models.PaymentMethodCOD); err != nil {
+		t.Fatalf("StartPayment: %v", err)
+	}
+
+	invoice, err := invoices.GetInvoice("ORDER_1")
+	if err != nil {
+		t.Fatalf("GetInvoice after confirmation: %v", err)
+	}
+	if invoice.Sequence != 1 {
+		t.Fatalf("invoice sequence = %d, want 1", invoice.Sequence)
+	}
+}
+
+// startOnlinePayment places ORDER_1 and starts its payment with the fake gateway
+func startOnlinePayment(t *testing.T) (*PaymentService, *models.Payment) {
+	t.Helper()
+
+	db := openTestDB(t)
+	t.Setenv("RTO_STORE", "db")
+	t.Setenv("PAYMENT_GATEWAY", "fake_gateway")
+	t.Setenv("APP_ENV", "development")
+	t.Setenv("FAKE_GATEWAY_WEBHOOK_SECRET", "s3cret")
+	seedRTOList(t, db, "C1", 101, 1, "SO-1")
+
+	order, err := NewOrderService().CreateOrder(CreateOrderInput{
+		OrderID: "ORDER_1",
+		UserID:  "buyer",
+		Items:   []models.OrderItem{{RTOCode: "C1", ProductID: "101", CatalogID: "900", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	service := NewPaymentService()
+	payment, _, err := service.StartPayment(order, models.PaymentMethodOnline)
+	if err != nil {
+		t.Fatalf("StartPayment: %v", err)
+	}
+	return service, payment
+}
+
+// assertRefundQueued checks that ORDER_1 has exactly one refund event
+func assertRefundQueued(t *testing.T) {
+	t.Helper()
+
+	var refunds int64
+	configs.DB.Model(&models.OutboxEvent{}).
+		Where("order_id = ? AND event_type = ?", "ORDER_1", models.OutboxEventPaymentRefund).
+		Count(&refunds)
+	if refunds != 1 {
+		t.Fatalf("%d refund events queued, want 1", refunds)
+	}
+}
+
+func TestCancellingAPaidOrderQueuesItsRefund(t *testing.T) {
+	service, payment := startOnlinePayment(t)
+	if _, err := service.applyStatus(payment, models.PaymentStatusSucceeded, ""); err != nil {
+		t.Fatalf("applyStatus: %v", err)
+	}
+
+	if _, err := NewOrderService().CancelOrder("ORDER_1", "buyer", "changed my mind"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	assertRefundQueued(t)
+
+	if _, err := NewOutboxService().DispatchDue(); err != nil {
+		t.Fatalf("DispatchDue: %v", err)
+	}
+	refunded, err := service.reload(payment)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if refunded.Status != models.PaymentStatusRefunded || refunded.RefundRef == "" {
+		t.Fatalf("payment is %s with refund ref %q, want refunded", refunded.Status, refunded.RefundRef)
+	}
+}
+
+func TestPaymentSucceedingAfterCancellationIsRefunded(t *testing.T) {
+	service, payment := startOnlinePayment(t)
+	if _, err := NewOrderService().CancelOrder("ORDER_1", "buyer", "changed my mind"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+
+	captured, err := service.applyStatus(payment, models.PaymentStatusSucceeded, "")
+	if err != nil {
+		t.Fatalf("applyStatus: %v", err)
+	}
+	if captured.Status != models.PaymentStatusSucceeded {
+		t.Fatalf("late success left the payment %s, want it recorded as succeeded", captured.Status)
+	}
+	assertRefundQueued(t)
+}
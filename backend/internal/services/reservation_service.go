@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrAlreadySold is returned when the RTO unit has already been sold to another buyer
+	ErrAlreadySold = errors.New("item already sold")
+
+	// ErrAlreadyReserved is returned when another buyer is currently checking out the unit
+	ErrAlreadyReserved = errors.New("item is reserved by another buyer")
+
+	// ErrReservationNotFound is returned when no active hold matches the request
+	ErrReservationNotFound = errors.New("reservation not found")
+)
+
+// ReservationService holds RTO units for a buyer during checkout
+type ReservationService struct {
+	db            *gorm.DB
+	ttl           time.Duration
+	sweepInterval time.Duration
+}
+
+// NewReservationService creates a new reservation service
+func NewReservationService() *ReservationService {
+	return &ReservationService{
+		db:            configs.DB,
+		ttl:           getEnvDuration("RESERVATION_TTL", 10*time.Minute),
+		sweepInterval: getEnvDuration("RESERVATION_SWEEP_INTERVAL", time.Minute),
+	}
+}
+
+// activeUnitKey identifies one RTO unit
+func activeUnitKey(code, productID, subOrderNum string) string {
+	return code + ":" + productID + ":" + subOrderNum
+}
+
+// activeUnitKeys returns the keys of the given units of a product at a code
+func activeUnitKeys(code, productID string, units models.RTOProducts) []string {
+	keys := make([]string, 0, len(units))
+	for _, unit := range units {
+		keys = append(keys, activeUnitKey(code, productID, unit.SubOrderNum))
+	}
+	return keys
+}
+
+// generateReservationID generates a unique reservation ID
+func generateReservationID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return "rsv_" + hex.EncodeToString(bytes)
+}
+
+// Hold reserves one of the given units of a product for the user until the
+// TTL elapses. Holding a product the user already holds a unit of extends
+// the existing hold.
+func (s *ReservationService) Hold(userID, code, productID, catalogID string, units models.RTOProducts) (*models.InventoryReservation, error) {
+	if code == "" {
+		return nil, fmt.Errorf("no RTO code available for user %s", userID)
+	}
+	if len(units) == 0 {
+		return nil, fmt.Errorf("product %s: %w", productID, ErrRTOUnitNotFound)
+	}
+
+	keys := activeUnitKeys(code, productID, units)
+	if err := s.expireUnits(s.db, keys); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.ttl)
+
+	// Extend an existing hold by the same user
+	var existing models.InventoryReservation
+	result := s.db.Where("active_unit IN ? AND user_id = ? AND status = ?", keys, userID, models.ReservationStatusHeld).Limit(1).Find(&existing)
+	if result.Error != nil {
+		return nil, fmt.Errorf("database error: %v", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		if err := s.db.Model(&existing).Update("expires_at", expiresAt).Error; err != nil {
+			return nil, fmt.Errorf("failed to extend reservation: %w", err)
+		}
+		return &existing, nil
+	}
+
+	var takenErr error
+	for i, unit := range units {
+		key := keys[i]
+		reservation := models.InventoryReservation{
+			ReservationID: generateReservationID(),
+			UserID:        userID,
+			Code:          code,
+			ProductID:     productID,
+			SubOrderNum:   unit.SubOrderNum,
+			CatalogID:     catalogID,
+			Status:        models.ReservationStatusHeld,
+			ActiveUnit:    &key,
+			ExpiresAt:     expiresAt,
+		}
+
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reservation)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to create reservation: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return &reservation, nil
+		}
+		takenErr = preferHeldError(takenErr, s.unitTakenError(s.db, key))
+	}
+	return nil, takenErr
+}
+
+// ClaimUnit sells one of the given units of the item's product to the user as
+// part of an order transaction and returns the item for that unit. A unit the
+// user already holds is preferred; units held by other buyers or already sold
+// are skipped.
+func (s *ReservationService) ClaimUnit(tx *gorm.DB, userID string, item models.OrderItem, units models.RTOProducts) (models.OrderItem, error) {
+	keys := activeUnitKeys(item.RTOCode, item.ProductID, units)
+
+	var heldKeys []string
+	err := tx.Model(&models.InventoryReservation{}).
+		Where("active_unit IN ? AND user_id = ? AND status = ? AND expires_at >= ?", keys, userID, models.ReservationStatusHeld, time.Now()).
+		Pluck("active_unit", &heldKeys).Error
+	if err != nil {
+		return item, fmt.Errorf("database error: %v", err)
+	}
+	heldByUser := make(map[string]bool, len(heldKeys))
+	for _, key := range heldKeys {
+		heldByUser[key] = true
+	}
+
+	ordered := make(models.RTOProducts, 0, len(units))
+	for i, unit := range units {
+		if heldByUser[keys[i]] {
+			ordered = append(ordered, unit)
+		}
+	}
+	for i, unit := range units {
+		if !heldByUser[keys[i]] {
+			ordered = append(ordered, unit)
+		}
+	}
+
+	var takenErr error
+	for _, unit := range ordered {
+		claimed := item
+		claimed.Quantity = 1
+		claimed.SubOrderNum = unit.SubOrderNum
+		claimed.RTOOrderDate = unit.OrderDate
+
+		err := s.ConvertToSale(tx, userID, claimed)
+		if err == nil {
+			return claimed, nil
+		}
+		if !errors.Is(err, ErrAlreadySold) && !errors.Is(err, ErrAlreadyReserved) {
+			return item, err
+		}
+		takenErr = preferHeldError(takenErr, err)
+	}
+	if takenErr == nil {
+		return item, fmt.Errorf("product %s: %w", item.ProductID, ErrRTOUnitNotFound)
+	}
+	return item, takenErr
+}
+
+// ConvertToSale marks the item's unit as sold to the user as part of an order
+// transaction. The user's own live hold is converted; without one the unit is
+// claimed directly. A unit held by another buyer is reported as
+// ErrAlreadyReserved rather than sold, as the hold may still lapse.
+func (s *ReservationService) ConvertToSale(tx *gorm.DB, userID string, item models.OrderItem) error {
+	unit := activeUnitKey(item.RTOCode, item.ProductID, item.SubOrderNum)
+	if err := s.expireUnits(tx, []string{unit}); err != nil {
+		return err
+	}
+
+	// Convert the user's own hold. The hold is looked up with a plain read and
+	// updated by primary key so that buyers racing for a free unit do not take
+	// gap locks on the unit index and deadlock each other.
+	var own models.InventoryReservation
+	found := tx.Where("active_unit = ? AND user_id = ? AND status = ?", unit, userID, models.ReservationStatusHeld).Limit(1).Find(&own)
+	if found.Error != nil {
+		return fmt.Errorf("database error: %v", found.Error)
+	}
+	if found.RowsAffected == 1 {
+		result := tx.Model(&models.InventoryReservation{}).
+			Where("id = ? AND status = ?", own.ID, models.ReservationStatusHeld).
+			Updates(map[string]interface{}{
+				"status":   models.ReservationStatusSold,
+				"order_id": item.OrderID,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to convert reservation: %w", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return nil
+		}
+	}
+
+	reservation := models.InventoryReservation{
+		ReservationID: generateReservationID(),
+		UserID:        userID,
+		Code:          item.RTOCode,
+		ProductID:     item.ProductID,
+		SubOrderNum:   item.SubOrderNum,
+		CatalogID:     item.CatalogID,
+		Status:        models.ReservationStatusSold,
+		ActiveUnit:    &unit,
+		OrderID:       item.OrderID,
+		ExpiresAt:     time.Now(),
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reservation)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create reservation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("product %s: %w", item.ProductID, s.unitTakenError(tx, unit))
+	}
+	return nil
+}
+
+// ClaimForSupplierReturn claims an expired unit for its return to the
+// supplier as part of tx, so that it cannot be sold while it is dropped from
+// the RTO list. It reports false when a buyer holds or bought the unit.
+// A unit already claimed for its return is reported as claimed again, so an
+// interrupted drop is retried.
+func (s *ReservationService) ClaimForSupplierReturn(tx *gorm.DB, code, productID, subOrderNum string) (bool, error) {
+	unit := activeUnitKey(code, productID, subOrderNum)
+	if err := s.expireUnits(tx, []string{unit}); err != nil {
+		return false, err
+	}
+
+	reservation := models.InventoryReservation{
+		ReservationID: generateReservationID(),
+		UserID:        rtoAgeActor,
+		Code:          code,
+		ProductID:     productID,
+		SubOrderNum:   subOrderNum,
+		Status:        models.ReservationStatusReturned,
+		ActiveUnit:    &unit,
+		ExpiresAt:     time.Now(),
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reservation)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create reservation: %w", result.Error)
+	}
+	if result.RowsAffected == 1 {
+		return true, nil
+	}
+
+	var existing models.InventoryReservation
+	found := tx.Where("active_unit = ?", unit).Limit(1).Find(&existing)
+	if found.Error != nil {
+		return false, fmt.Errorf("database error: %v", found.Error)
+	}
+	return found.RowsAffected == 1 && existing.Status == models.ReservationStatusReturned, nil
+}
+
+// IsAvailable reports whether the user could still buy the unit right now
+func (s *ReservationService) IsAvailable(userID, code, productID, subOrderNum string) (bool, error) {
+	var existing models.InventoryReservation
+	err := s.db.Where("active_unit = ?", activeUnitKey(code, productID, subOrderNum)).First(&existing).Error
+	if err == gorm.ErrRecordNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("database error: %v", err)
+	}
+
+	if existing.Status != models.ReservationStatusHeld {
+		return false, nil
+	}
+	// A hold only blocks other buyers until it expires
+	return existing.UserID == userID || time.Now().After(existing.ExpiresAt), nil
+}
+
+// unitTakenError explains why a unit could not be claimed: ErrAlreadyReserved
+// while another buyer holds it, ErrAlreadySold otherwise
+func (s *ReservationService) unitTakenError(db *gorm.DB, unit string) error {
+	var existing models.InventoryReservation
+	result := db.Where("active_unit = ?", unit).Limit(1).Find(&existing)
+	if result.Error == nil && result.RowsAffected == 1 && existing.Status == models.ReservationStatusHeld {
+		return ErrAlreadyReserved
+	}
+	return ErrAlreadySold
+}
+
+// preferHeldError keeps ErrAlreadyReserved over ErrAlreadySold when several
+// units were tried, as a held unit may still become available
+func preferHeldError(current, next error) error {
+	if current == nil || errors.Is(next, ErrAlreadyReserved) {
+		return next
+	}
+	return current
+}
+
+// Release gives up a hold owned by the user
+func (s *ReservationService) Release(userID, reservationID string) error {
+	result := s.db.Model(&models.InventoryReservation{}).
+		Where("reservation_id = ? AND user_id = ? AND status = ?", reservationID, userID, models.ReservationStatusHeld).
+		Updates(map[string]interface{}{
+			"status":      models.ReservationStatusReleased,
+			"active_unit": nil,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to release reservation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrReservationNotFound
+	}
+	return nil
+}
+
+// ReleaseForOrder puts every unit sold in an order back into the pool
+func (s *ReservationService) ReleaseForOrder(tx *gorm.DB, orderID string) error {
+	err := tx.Model(&models.InventoryReservation{}).
+		Where("order_id = ? AND status = ?", orderID, models.ReservationStatusSold).
+		Updates(map[string]interface{}{
+			"status":      models.ReservationStatusReleased,
+			"active_unit": nil,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release reservations for order %s: %w", orderID, err)
+	}
+	return nil
+}
+
+// ExpireStale frees every hold whose TTL has elapsed and returns how many were freed
+func (s *ReservationService) ExpireStale() (int64, error) {
+	result := s.db.Model(&models.InventoryReservation{}).
+		Where("status = ? AND expires_at < ?", models.ReservationStatusHeld, time.Now()).
+		Updates(map[string]interface{}{
+			"status":      models.ReservationStatusExpired,
+			"active_unit": nil,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to expire reservations: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// StartExpirySweeper periodically expires stale holds until the context is cancelled
+func (s *ReservationService) StartExpirySweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.ExpireStale()
+			if err != nil {
+				fmt.Printf("Warning: Reservation sweep failed: %v\n", err)
+			} else if expired > 0 {
+				fmt.Printf("Expired %d stale reservations\n", expired)
+			}
+		}
+	}
+}
+
+// expireUnits frees stale holds on the given units so they can be claimed
+// again. Stale holds are found with a plain read and expired by primary key
+// to keep the unit index free of gap locks.
+func (s *ReservationService) expireUnits(db *gorm.DB, units []string) error {
+	var staleIDs []uint
+	err := db.Model(&models.InventoryReservation{}).
+		Where("active_unit IN ? AND status = ? AND expires_at < ?", units, models.ReservationStatusHeld, time.Now()).
+		Pluck("id", &staleIDs).Error
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if len(staleIDs) == 0 {
+		return nil
+	}
+
+	err = db.Model(&models.InventoryReservation{}).
+		Where("id IN ? AND status = ?", staleIDs, models.ReservationStatusHeld).
+		Updates(map[string]interface{}{
+			"status":      models.ReservationStatusExpired,
+			"active_unit": nil,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to expire reservation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"meesho-clone/internal/pii"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the MySQL database named by TEST_DB_DSN, migrates
+// the order tables and empties them. Tests that need it are skipped when the
+// variable is not set, e.g.
+//
+//	TEST_DB_DSN='root:pass@tcp(localhost:3306)/meesho_test?parseTime=True&loc=Local'
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN not set")
+	}
+
+	// Order shipping phones are encrypted, so any key will do
+	if err := pii.Configure(make([]byte, 32), make([]byte, 32)); err != nil {
+		t.Fatalf("failed to configure encryption: %v", err)
+	}
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	tables := []interface{}{
+		&models.PriceProductInfo{},
+		&models.RTOList{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.OrderStatusHistory{},
+		&models.OutboxEvent{},
+		&models.InventoryReservation{},
+		&models.CartItem{},
+		&models.RTOAgePolicy{},
+		&models.Payment{},
+		&models.Invoice{},
+		&models.InvoiceSequence{},
+		&models.OTPChallenge{},
+		&models.ServiceablePincode{},
+		&models.IdempotencyKey{},
+		&models.SupplierReturn{},
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	for _, table := range tables {
+		if err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(table).Error; err != nil {
+			t.Fatalf("failed to clear %T: %v", table, err)
+		}
+	}
+
+	previous := configs.DB
+	configs.DB = db
+	t.Cleanup(func() { configs.DB = previous })
+	return db
+}
+
+// seedRTOList puts units of a product, returned daysAgo days ago, into the
+// rto_list row of a code and prices the product at 100
+func seedRTOList(t *testing.T, db *gorm.DB, code string, productID int64, daysAgo int, subOrderNums ...string) {
+	t.Helper()
+
+	orderDate := time.Now().AddDate(0, 0, -daysAgo).Format("2006-01-02")
+	row := models.RTOList{Code: code}
+	for _, subOrderNum := range subOrderNums {
+		row.Products = append(row.Products, models.RTOProduct{
+			RTOCount:    1,
+			CatalogID:   900,
+			ProductID:   productID,
+			OrderDate:   orderDate,
+			SubOrderNum: subOrderNum,
+		})
+	}
+	if err := db.Create(&row).Error; err != nil {
+		t.Fatalf("failed to seed RTO list: %v", err)
+	}
+
+	info := models.PriceProductInfo{
+		ProductID:           strconv.FormatInt(productID, 10),
+		CatalogID:           "900",
+		Category:            "Sarees",
+		SupplierListedPrice: 100,
+	}
+	if err := db.Where("product_id = ?", info.ProductID).FirstOrCreate(&info).Error; err != nil {
+		t.Fatalf("failed to seed price_product_info: %v", err)
+	}
+}
+
+// raceConvertToSale sells one unit to many buyers at once and returns the
+// buyers that succeeded along with the errors of the others
+func raceConvertToSale(t *testing.T, service *ReservationService, db *gorm.DB, item models.OrderItem, buyers int) ([]string, []error) {
+	t.Helper()
+
+	var (
+		mu      sync.Mutex
+		winners []string
+		losses  []error
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+	)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("buyer_%d", i)
+			order := item
+			order.OrderID = fmt.Sprintf("ORDER_%d", i)
+
+			<-start
+			err := db.Transaction(func(tx *gorm.DB) error {
+				return service.ConvertToSale(tx, userID, order)
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				winners = append(winners, userID)
+			} else {
+				losses = append(losses, err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return winners, losses
+}
+
+func TestConvertToSaleSellsUnitToExactlyOneBuyer(t *testing.T) {
+	db := openTestDB(t)
+	service := NewReservationService()
+
+	item := models.OrderItem{RTOCode: "C1", ProductID: "101", CatalogID: "900", SubOrderNum: "SO-1", Quantity: 1}
+	winners, losses := raceConvertToSale(t, service, db, item, 20)
+
+	if len(winners) != 1 {
+		t.Fatalf("%d buyers bought the unit, want exactly 1 (%v)", len(winners), winners)
+	}
+	for _, err := range losses {
+		if !errors.Is(err, ErrAlreadySold) && !isDeadlock(err) {
+			t.Errorf("losing buyer got %v, want %v", err, ErrAlreadySold)
+		}
+	}
+
+	var live int64
+	db.Model(&models.InventoryReservation{}).Where("active_unit IS NOT NULL").Count(&live)
+	if live != 1 {
+		t.Fatalf("%d live reservations for the unit, want 1", live)
+	}
+}
+
+func TestConvertToSaleReportsUnitHeldByAnotherBuyer(t *testing.T) {
+	db := openTestDB(t)
+	service := NewReservationService()
+
+	units := models.RTOProducts{{ProductID: 101, SubOrderNum: "SO-1"}}
+	if _, err := service.Hold("holder", "C1", "101", "900", units); err != nil {
+		t.Fatalf("Hold: %v", err)
+	}
+
+	item := models.OrderItem{OrderID: "ORDER_1", RTOCode: "C1", ProductID: "101", SubOrderNum: "SO-1", Quantity: 1}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return service.ConvertToSale(tx, "buyer", item)
+	})
+	if !errors.Is(err, ErrAlreadyReserved) {
+		t.Fatalf("ConvertToSale on a held unit returned %v, want %v", err, ErrAlreadyReserved)
+	}
+
+	// The holder can still buy it
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return service.ConvertToSale(tx, "holder", item)
+	})
+	if err != nil {
+		t.Fatalf("holder could not buy the held unit: %v", err)
+	}
+}
+
+func TestSecondUnitOfProductCanBeSold(t *testing.T) {
+	db := openTestDB(t)
+	service := NewReservationService()
+
+	for i, subOrderNum := range []string{"SO-1", "SO-2"} {
+		item := models.OrderItem{
+			OrderID:     fmt.Sprintf("ORDER_%d", i),
+			RTOCode:     "C1",
+			ProductID:   "101",
+			SubOrderNum: subOrderNum,
+			Quantity:    1,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return service.ConvertToSale(tx, fmt.Sprintf("buyer_%d", i), item)
+		})
+		if err != nil {
+			t.Fatalf("selling unit %s: %v", subOrderNum, err)
+		}
+	}
+}
+
+// raceCreateOrder places one order per buyer for the same product at once and
+// returns how many succeeded along with the errors of the others
+func raceCreateOrder(t *testing.T, buyers int, item models.OrderItem) (int, []error) {
+	t.Helper()
+
+	service := NewOrderService()
+
+	var (
+		mu     sync.Mutex
+		placed int
+		losses []error
+		wg     sync.WaitGroup
+		start  = make(chan struct{})
+	)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, err := service.CreateOrder(CreateOrderInput{
+				OrderID: fmt.Sprintf("ORDER_%d", i),
+				UserID:  fmt.Sprintf("buyer_%d", i),
+				Items:   []models.OrderItem{item},
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				placed++
+			} else {
+				losses = append(losses, err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return placed, losses
+}
+
+func TestParallelOrdersForOneUnitHaveOneWinner(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("RTO_STORE", "db")
+	seedRTOList(t, db, "C1", 101, 1, "SO-1")
+
+	item := models.OrderItem{RTOCode: "C1", ProductID: "101", CatalogID: "900", Quantity: 1, UnitPrice: 100}
+	placed, losses := raceCreateOrder(t, 20, item)
+
+	if placed != 1 {
+		t.Fatalf("%d orders placed for one unit, want exactly 1", placed)
+	}
+	for _, err := range losses {
+		if !errors.Is(err, ErrAlreadySold) {
+			t.Errorf("losing order got %v, want %v", err, ErrAlreadySold)
+		}
+	}
+
+	var orders, drops int64
+	db.Model(&models.Order{}).Count(&orders)
+	db.Model(&models.OutboxEvent{}).Where("event_type = ?", models.OutboxEventRTODrop).Count(&drops)
+	if orders != 1 || drops != 1 {
+		t.Fatalf("got %d orders and %d drop events, want 1 of each", orders, drops)
+	}
+}
+
+func TestParallelOrdersSellEachUnitOfAProductOnce(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("RTO_STORE", "db")
+	seedRTOList(t, db, "C1", 101, 1, "SO-1", "SO-2", "SO-3")
+
+	item := models.OrderItem{RTOCode: "C1", ProductID: "101", CatalogID: "900", Quantity: 1, UnitPrice: 100}
+	placed, _ := raceCreateOrder(t, 20, item)
+
+	if placed != 3 {
+		t.Fatalf("%d orders placed for three units, want 3", placed)
+	}
+
+	var soldUnits []string
+	db.Model(&models.OrderItem{}).Order("sub_order_num").Pluck("sub_order_num", &soldUnits)
+	if fmt.Sprint(soldUnits) != "[SO-1 SO-2 SO-3]" {
+		t.Fatalf("sold units %v, want each of SO-1, SO-2 and SO-3 once", soldUnits)
+	}
+}
+
+func TestCreateOrderRejectsItemWithoutRTOCode(t *testing.T) {
+	_, err := NewOrderService().CreateOrder(CreateOrderInput{
+		OrderID: "ORDER_1",
+		UserID:  "buyer",
+		Items:   []models.OrderItem{{ProductID: "101", CatalogID: "900", Quantity: 1}},
+	})
+	if !errors.Is(err, ErrNoRTOCode) {
+		t.Fatalf("CreateOrder without a code returned %v, want %v", err, ErrNoRTOCode)
+	}
+}
+
+func TestCreateOrderChargesMarkdownOfClaimedUnit(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("RTO_STORE", "db")
+	seedRTOList(t, db, "C1", 101, 8, "SO-1")
+
+	order, err := NewOrderService().CreateOrder(CreateOrderInput{
+		OrderID: "ORDER_1",
+		UserID:  "buyer",
+		Items:   []models.OrderItem{{RTOCode: "C1", ProductID: "101", CatalogID: "900", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	// The default policy takes 10% off after 7 days
+	item := order.Items[0]
+	if item.UnitPrice != 100 || item.MarkdownPercent != 10 || order.TotalAmount != 90 {
+		t.Fatalf("charged %.2f for a unit listed at %.2f with %d%% off, want 90.00 for 100.00 with 10%% off",
+			order.TotalAmount, item.UnitPrice, item.MarkdownPercent)
+	}
+}
+
+func TestCreateOrderRejectsExpiredUnit(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("RTO_STORE", "db")
+	seedRTOList(t, db, "C1", 101, 45, "SO-1")
+
+	_, err := NewOrderService().CreateOrder(CreateOrderInput{
+		OrderID: "ORDER_1",
+		UserID:  "buyer",
+		Items:   []models.OrderItem{{RTOCode: "C1", ProductID: "101", CatalogID: "900", Quantity: 1}},
+	})
+	if !errors.Is(err, ErrRTOUnitExpired) {
+		t.Fatalf("CreateOrder for an expired unit returned %v, want %v", err, ErrRTOUnitExpired)
+	}
+}
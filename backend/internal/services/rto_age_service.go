@@ -0,0 +1,408 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRTOUnitExpired is returned when the units of an ordered product have
+// outlived their age policy and are no longer sold
+var ErrRTOUnitExpired = errors.New("item has expired at this RTO location")
+
+// rtoAgeActor owns the claims on units being returned to their suppliers
+const rtoAgeActor = "system:rto-age"
+
+// RTOAgeService applies the per-category age policies to RTO units: it marks
+// down units that have been waiting, hides them once they expire and sends
+// expired units back to their suppliers
+type RTOAgeService struct {
+	db                 *gorm.DB
+	store              RTOStore
+	reservationService *ReservationService
+	defaultPolicy      models.RTOAgePolicy
+	sweepInterval      time.Duration
+}
+
+// RTOUnitAge describes where an RTO unit stands under its age policy
+type RTOUnitAge struct {
+	AgeDays         int
+	MarkdownPercent int
+	ExpiresAt       time.Time
+	Expired         bool
+}
+
+// RTOAgePolicies holds the configured policies keyed by category
+type RTOAgePolicies struct {
+	byCategory map[string]models.RTOAgePolicy
+	fallback   models.RTOAgePolicy
+}
+
+// NewRTOAgeService creates a new RTO age service. Categories without a policy,
+// when no "*" policy is configured either, are hidden after
+// RTO_HIDE_AFTER_DAYS days (30) with 10% off after 7 days and 20% after 14.
+func NewRTOAgeService() *RTOAgeService {
+	return &RTOAgeService{
+		db:                 configs.DB,
+		store:              NewRTOStore(),
+		reservationService: NewReservationService(),
+		defaultPolicy: models.RTOAgePolicy{
+			Category:      models.RTOAgePolicyDefault,
+			HideAfterDays: getEnvInt("RTO_HIDE_AFTER_DAYS", 30),
+			MarkdownSteps: models.MarkdownSteps{
+				{AfterDays: 7, Percent: 10},
+				{AfterDays: 14, Percent: 20},
+			},
+		},
+		sweepInterval: getEnvDuration("RTO_EXPIRY_SWEEP_INTERVAL", time.Hour),
+	}
+}
+
+// For returns the policy that applies to a category
+func (p RTOAgePolicies) For(category string) models.RTOAgePolicy {
+	if policy, exists := p.byCategory[strings.ToLower(strings.TrimSpace(category))]; exists {
+		return policy
+	}
+	return p.fallback
+}
+
+// LoadPolicies reads the configured age policies
+func (s *RTOAgeService) LoadPolicies() (RTOAgePolicies, error) {
+	policies := RTOAgePolicies{
+		byCategory: make(map[string]models.RTOAgePolicy),
+		fallback:   s.defaultPolicy,
+	}
+
+	var rows []models.RTOAgePolicy
+	if err := s.db.Find(&rows).Error; err != nil {
+		return policies, fmt.Errorf("database error: %v", err)
+	}
+
+	for _, row := range rows {
+		if row.Category == models.RTOAgePolicyDefault {
+			policies.fallback = row
+			continue
+		}
+		policies.byCategory[strings.ToLower(row.Category)] = row
+	}
+	return policies, nil
+}
+
+// ListPolicies returns the configured age policies
+func (s *RTOAgeService) ListPolicies() ([]models.RTOAgePolicy, error) {
+	var policies []models.RTOAgePolicy
+	if err := s.db.Order("category").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return policies, nil
+}
+
+// SetPolicy creates or replaces the age policy of a category
+func (s *RTOAgeService) SetPolicy(category string, req models.UpdateRTOAgePolicyRequest, actor string) (*models.RTOAgePolicy, error) {
+	category = strings.TrimSpace(category)
+	if category == "" {
+		return nil, fmt.Errorf("category is required")
+	}
+	for _, step := range req.MarkdownSteps {
+		if step.AfterDays >= req.HideAfterDays {
+			return nil, fmt.Errorf("markdown after %d days would never apply to units hidden after %d days", step.AfterDays, req.HideAfterDays)
+		}
+	}
+
+	policy := models.RTOAgePolicy{
+		Category:      category,
+		HideAfterDays: req.HideAfterDays,
+		MarkdownSteps: models.MarkdownSteps(req.MarkdownSteps),
+		UpdatedBy:     actor,
+	}
+	if policy.MarkdownSteps == nil {
+		policy.MarkdownSteps = models.MarkdownSteps{}
+	}
+
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "category"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hide_after_days", "markdown_steps", "updated_by", "updated_at"}),
+	}).Create(&policy).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save RTO age policy: %w", err)
+	}
+
+	if err := s.db.Where("category = ?", category).First(&policy).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return &policy, nil
+}
+
+// UnitAge works out the age of a unit under a policy. Units without a
+// readable order date are treated as fresh and never expire.
+func (s *RTOAgeService) UnitAge(policy models.RTOAgePolicy, unit models.RTOProduct, now time.Time) (RTOUnitAge, bool) {
+	orderDate, err := time.ParseInLocation("2006-01-02", unit.OrderDate, time.Local)
+	if err != nil {
+		return RTOUnitAge{}, false
+	}
+
+	ageDays := int(now.Sub(orderDate).Hours() / 24)
+	if ageDays < 0 {
+		ageDays = 0
+	}
+	expiresAt := orderDate.AddDate(0, 0, policy.HideAfterDays)
+
+	return RTOUnitAge{
+		AgeDays:         ageDays,
+		MarkdownPercent: policy.MarkdownPercent(ageDays),
+		ExpiresAt:       expiresAt,
+		Expired:         !now.Before(expiresAt),
+	}, true
+}
+
+// ListableUnits returns the units that have not expired, oldest first so the
+// oldest stock is shown and sold first. Undated units come last.
+func (s *RTOAgeService) ListableUnits(policy models.RTOAgePolicy, units models.RTOProducts, now time.Time) models.RTOProducts {
+	ageDays := make(map[int]int, len(units))
+	listable := make(models.RTOProducts, 0, len(units))
+	for _, unit := range units {
+		age, dated := s.UnitAge(policy, unit, now)
+		if dated && age.Expired {
+			continue
+		}
+		if dated {
+			ageDays[len(listable)] = age.AgeDays
+		} else {
+			ageDays[len(listable)] = -1
+		}
+		listable = append(listable, unit)
+	}
+
+	order := make([]int, len(listable))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return ageDays[order[a]] > ageDays[order[b]]
+	})
+
+	sorted := make(models.RTOProducts, len(listable))
+	for i, index := range order {
+		sorted[i] = listable[index]
+	}
+	return sorted
+}
+
+// ListedUnit returns the unit that is shown for a product and its age, or
+// no age when the unit is undated. It returns false when every unit has
+// expired.
+func (s *RTOAgeService) ListedUnit(policy models.RTOAgePolicy, units models.RTOProducts, now time.Time) (models.RTOProduct, *RTOUnitAge, bool) {
+	listable := s.ListableUnits(policy, units, now)
+	if len(listable) == 0 {
+		return models.RTOProduct{}, nil, false
+	}
+
+	unit := listable[0]
+	if age, dated := s.UnitAge(policy, unit, now); dated {
+		return unit, &age, true
+	}
+	return unit, nil, true
+}
+
+// EffectivePrice is the price charged for an RTO unit: the supplier listed
+// price less the unit's age markdown
+func EffectivePrice(listedPrice float64, markdownPercent int) float64 {
+	return roundCurrency(listedPrice * float64(100-markdownPercent) / 100)
+}
+
+// ExpiresInHint describes how many days an RTO unit stays listed. Units are
+// hidden from the start of their expiry day.
+func ExpiresInHint(expiresAt, now time.Time) string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	days := int(math.Round(expiresAt.Sub(today).Hours() / 24))
+	if days <= 1 {
+		return "Expires today"
+	}
+	return fmt.Sprintf("Expires in %d days", days)
+}
+
+// StartExpirySweeper periodically returns expired RTO units to their
+// suppliers until the context is cancelled
+func (s *RTOAgeService) StartExpirySweeper(ctx context.Context) {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			returned, err := s.SweepExpired()
+			if err != nil {
+				fmt.Printf("Warning: RTO expiry sweep failed: %v\n", err)
+			} else if returned > 0 {
+				fmt.Printf("Returned %d expired RTO units to suppliers\n", returned)
+			}
+		}
+	}
+}
+
+// SweepExpired queues every expired RTO unit for return to its supplier and
+// drops it from the RTO list. A code that fails is retried on the next sweep.
+func (s *RTOAgeService) SweepExpired() (int, error) {
+	policies, err := s.LoadPolicies()
+	if err != nil {
+		return 0, err
+	}
+
+	codes, err := s.rtoCodes()
+	if err != nil {
+		return 0, err
+	}
+
+	returned := 0
+	for _, code := range codes {
+		count, err := s.sweepCode(code, policies, time.Now())
+		if err != nil {
+			fmt.Printf("Warning: Failed to sweep expired RTO units of code %s: %v\n", code, err)
+		}
+		returned += count
+	}
+	return returned, nil
+}
+
+// rtoCodes returns every code that may hold RTO units
+func (s *RTOAgeService) rtoCodes() ([]string, error) {
+	var mappedCodes, listedCodes []string
+	if err := s.db.Model(&models.UserMapping{}).Where("code <> ''").Distinct().Pluck("code", &mappedCodes).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	if err := s.db.Model(&models.RTOList{}).Where("code <> ''").Distinct().Pluck("code", &listedCodes).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	var codes []string
+	for _, code := range append(mappedCodes, listedCodes...) {
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+// sweepCode returns the expired units of one code and reports how many were returned
+func (s *RTOAgeService) sweepCode(code string, policies RTOAgePolicies, now time.Time) (int, error) {
+	units, err := s.store.FetchByCode(code)
+	if err != nil {
+		return 0, err
+	}
+	if len(units) == 0 {
+		return 0, nil
+	}
+
+	productInfos, err := s.productInfos(units)
+	if err != nil {
+		return 0, err
+	}
+
+	returned := 0
+	for _, unit := range units {
+		info := productInfos[unit.ProductID]
+		age, dated := s.UnitAge(policies.For(info.Category), unit, now)
+		if !dated || !age.Expired {
+			continue
+		}
+
+		// Only the expired unit is dropped, so it has to be told apart from
+		// the other units of its product
+		if unit.SubOrderNum == "" {
+			fmt.Printf("Warning: Expired unit of product %d at code %s has no sub order number, not returning it\n", unit.ProductID, code)
+			continue
+		}
+
+		supplierReturn := models.SupplierReturn{
+			UnitKey:     supplierReturnKey(code, unit),
+			Code:        code,
+			ProductID:   unit.ProductID,
+			CatalogID:   unit.CatalogID,
+			SubOrderNum: unit.SubOrderNum,
+			SupplierID:  info.SupplierID,
+			Category:    info.Category,
+			OrderDate:   unit.OrderDate,
+			AgeDays:     age.AgeDays,
+			Status:      models.SupplierReturnPending,
+		}
+
+		// Claim the unit so no buyer can order it while it is dropped, and
+		// queue its return before dropping so a unit is never lost. Units a
+		// buyer holds or bought are left to their reservation.
+		claimed := false
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			claimed, err = s.reservationService.ClaimForSupplierReturn(tx, code, strconv.FormatInt(unit.ProductID, 10), unit.SubOrderNum)
+			if err != nil || !claimed {
+				return err
+			}
+
+			// Replays are ignored
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&supplierReturn).Error; err != nil {
+				return fmt.Errorf("failed to queue supplier return: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return returned, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.store.DropUnit(code, unit.ProductID, unit.SubOrderNum); err != nil {
+			return returned, fmt.Errorf("failed to drop unit %s: %w", unit.SubOrderNum, err)
+		}
+		returned++
+	}
+	return returned, nil
+}
+
+// productInfos loads the price_product_info rows of the given units keyed by product ID
+func (s *RTOAgeService) productInfos(units models.RTOProducts) (map[int64]models.PriceProductInfo, error) {
+	productIDs := make([]string, 0, len(units))
+	for _, unit := range units {
+		productIDs = append(productIDs, strconv.FormatInt(unit.ProductID, 10))
+	}
+
+	var rows []models.PriceProductInfo
+	if err := s.db.Where("product_id IN ?", productIDs).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query price_product_info table: %w", err)
+	}
+
+	infos := make(map[int64]models.PriceProductInfo, len(rows))
+	for _, row := range rows {
+		if productID, ok := parseRTOUnitProductID(row.ProductID); ok {
+			infos[productID] = row
+		}
+	}
+	return infos, nil
+}
+
+// supplierReturnKey identifies an RTO unit in the supplier return queue
+func supplierReturnKey(code string, unit models.RTOProduct) string {
+	return code + ":" + unit.SubOrderNum
+}
+
+// parseRTOUnitProductID converts a price_product_info product ID to the ID used in RTO lists
+func parseRTOUnitProductID(productID string) (int64, bool) {
+	parsed, err := strconv.ParseInt(strings.TrimPrefix(productID, "s-"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return parsed, true
+}
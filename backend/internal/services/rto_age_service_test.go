@@ -0,0 +1,116 @@
+package services
+
+import (
+	"meesho-clone/internal/models"
+	"testing"
+	"time"
+)
+
+func testAgePolicy() models.RTOAgePolicy {
+	return models.RTOAgePolicy{
+		HideAfterDays: 30,
+		MarkdownSteps: models.MarkdownSteps{
+			{AfterDays: 7, Percent: 10},
+			{AfterDays: 14, Percent: 20},
+		},
+	}
+}
+
+func TestListableUnitsDropsExpiredAndSortsOldestFirst(t *testing.T) {
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.Local)
+	units := models.RTOProducts{
+		{SubOrderNum: "fresh", OrderDate: "2025-03-29"},
+		{SubOrderNum: "undated"},
+		{SubOrderNum: "expired", OrderDate: "2025-02-01"},
+		{SubOrderNum: "old", OrderDate: "2025-03-10"},
+	}
+
+	listable := (&RTOAgeService{}).ListableUnits(testAgePolicy(), units, now)
+
+	var got []string
+	for _, unit := range listable {
+		got = append(got, unit.SubOrderNum)
+	}
+	if len(got) != 3 || got[0] != "old" || got[1] != "fresh" || got[2] != "undated" {
+		t.Fatalf("listable units %v, want [old fresh undated]", got)
+	}
+}
+
+func TestListedUnitIsTheOldestListedUnit(t *testing.T) {
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.Local)
+	service := &RTOAgeService{}
+
+	unit, age, listed := service.ListedUnit(testAgePolicy(), models.RTOProducts{
+		{SubOrderNum: "fresh", OrderDate: "2025-03-29"},
+		{SubOrderNum: "old", OrderDate: "2025-03-15"},
+	}, now)
+	if !listed || unit.SubOrderNum != "old" || age == nil || age.MarkdownPercent != 20 {
+		t.Fatalf("listed %v unit %q with age %+v, want the old unit at 20%% off", listed, unit.SubOrderNum, age)
+	}
+
+	if _, _, listed := service.ListedUnit(testAgePolicy(), models.RTOProducts{{OrderDate: "2025-01-01"}}, now); listed {
+		t.Fatal("an expired unit was listed")
+	}
+}
+
+func TestEffectivePrice(t *testing.T) {
+	cases := []struct {
+		listed   float64
+		markdown int
+		want     float64
+	}{
+		{499, 0, 499},
+		{499, 10, 449.1},
+		{333.33, 20, 266.66},
+	}
+	for _, tc := range cases {
+		if got := EffectivePrice(tc.listed, tc.markdown); got != tc.want {
+			t.Errorf("EffectivePrice(%v, %d) = %v, want %v", tc.listed, tc.markdown, got, tc.want)
+		}
+	}
+}
+
+func TestSweepLeavesSoldExpiredUnitsListed(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("RTO_STORE", "db")
+	seedRTOList(t, db, "C1", 101, 40, "SO-1", "SO-2")
+
+	soldUnit := activeUnitKey("C1", "101", "SO-1")
+	err := db.Create(&models.InventoryReservation{
+		ReservationID: "res_sold",
+		UserID:        "buyer",
+		Code:          "C1",
+		ProductID:     "101",
+		SubOrderNum:   "SO-1",
+		Status:        models.ReservationStatusSold,
+		ActiveUnit:    &soldUnit,
+		OrderID:       "ORDER_1",
+		ExpiresAt:     time.Now(),
+	}).Error
+	if err != nil {
+		t.Fatalf("failed to seed sold reservation: %v", err)
+	}
+
+	service := NewRTOAgeService()
+	policies, err := service.LoadPolicies()
+	if err != nil {
+		t.Fatalf("LoadPolicies: %v", err)
+	}
+	returned, err := service.sweepCode("C1", policies, time.Now())
+	if err != nil || returned != 1 {
+		t.Fatalf("sweepCode() = %d, %v, want 1 returned unit", returned, err)
+	}
+
+	units, err := service.store.FetchByCode("C1")
+	if err != nil {
+		t.Fatalf("FetchByCode: %v", err)
+	}
+	if len(units) != 1 || units[0].SubOrderNum != "SO-1" {
+		t.Fatalf("units left listed: %+v, want only the sold SO-1", units)
+	}
+
+	available, err := NewReservationService().IsAvailable("buyer", "C1", "101", "SO-2")
+	if err != nil || available {
+		t.Fatalf("IsAvailable(SO-2) = %v, %v, want the returned unit unavailable", available, err)
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"meesho-clone/internal/models"
+	"net/http"
+	"os"
+	"time"
+)
+
+// RTOItem represents a single RTO item in the response
+type RTOItem struct {
+	CatalogID   int64  `json:"catalog_id"`
+	OrderDate   string `json:"order_date"`
+	ProductID   int64  `json:"product_id"`
+	RTOCount    int    `json:"rto_count"`
+	SubOrderNum string `json:"sub_order_num"`
+}
+
+// RTOResponse represents the response from the RTO API
+type RTOResponse struct {
+	Code       string             `json:"code"`
+	RTOList    map[string]RTOItem `json:"rto_list"`
+	Success    bool               `json:"success"`
+	TotalItems int                `json:"total_items"`
+}
+
+// HTTPRTOStore reads and updates RTO lists through the external RTO service
+type HTTPRTOStore struct {
+	rtoAPIURL        string
+	rtoRestoreAPIURL string
+	rtoDropUnitURL   string
+	httpClient       *http.Client
+}
+
+// NewHTTPRTOStore creates an RTO store backed by the external RTO service
+func NewHTTPRTOStore() *HTTPRTOStore {
+	// Get RTO API URL from environment variable with fallback
+	rtoAPIURL := os.Getenv("RTO_API_URL")
+	if rtoAPIURL == "" {
+		rtoAPIURL = "http://localhost:3001/rto/fetch"
+	}
+
+	rtoRestoreAPIURL := os.Getenv("RTO_RESTORE_API_URL")
+	if rtoRestoreAPIURL == "" {
+		rtoRestoreAPIURL = "http://localhost:3001/rto/add"
+	}
+
+	rtoDropUnitURL := os.Getenv("RTO_DROP_UNIT_API_URL")
+	if rtoDropUnitURL == "" {
+		rtoDropUnitURL = "http://localhost:3001/rto/delete-by-unit"
+	}
+
+	return &HTTPRTOStore{
+		rtoAPIURL:        rtoAPIURL,
+		rtoRestoreAPIURL: rtoRestoreAPIURL,
+		rtoDropUnitURL:   rtoDropUnitURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// FetchByCode implements RTOStore
+func (s *HTTPRTOStore) FetchByCode(code string) (models.RTOProducts, error) {
+	// Prepare the API URL
+	apiURL := fmt.Sprintf("%s/%s", s.rtoAPIURL, code)
+
+	fmt.Printf("Calling RTO API: %s\n", apiURL)
+
+	// Create HTTP request
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RTO request: %w", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+
+	// Make the request
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call RTO API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RTO response: %w", err)
+	}
+
+	// Check HTTP status
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("RTO API returned status %d: %s", resp.StatusCode, string(responseBody))
+	}
+
+	// Parse response
+	var rtoResponse RTOResponse
+	if err := json.Unmarshal(responseBody, &rtoResponse); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal RTO response: %w", err)
+	}
+
+	// Check if the response indicates success
+	if !rtoResponse.Success {
+		return nil, fmt.Errorf("RTO API returned error: success field is false")
+	}
+
+	products := make(models.RTOProducts, 0, len(rtoResponse.RTOList))
+	for _, rtoItem := range rtoResponse.RTOList {
+		products = append(products, models.RTOProduct{
+			RTOCount:    rtoItem.RTOCount,
+			CatalogID:   rtoItem.CatalogID,
+			OrderDate:   rtoItem.OrderDate,
+			ProductID:   rtoItem.ProductID,
+			SubOrderNum: rtoItem.SubOrderNum,
+		})
+	}
+	return products, nil
+}
+
+// Add implements RTOStore. The sub order number and order date are sent so
+// the unit keeps its identity and age.
+func (s *HTTPRTOStore) Add(code string, product models.RTOProduct) error {
+	return s.sendRTORequest("POST", s.rtoRestoreAPIURL, RTODropRequest{
+		Code:        code,
+		ProductID:   int(product.ProductID),
+		CatalogID:   int(product.CatalogID),
+		SubOrderNum: product.SubOrderNum,
+		OrderDate:   product.OrderDate,
+	})
+}
+
+// DropUnit implements RTOStore
+func (s *HTTPRTOStore) DropUnit(code string, productID int64, subOrderNum string) error {
+	return s.sendRTORequest("DELETE", s.rtoDropUnitURL, RTODropRequest{
+		Code:        code,
+		ProductID:   int(productID),
+		SubOrderNum: subOrderNum,
+	})
+}
+
+// sendRTORequest sends a JSON request to the RTO service and checks for a 200 response
+func (s *HTTPRTOStore) sendRTORequest(method, apiURL string, payload RTODropRequest) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal RTO request: %w", err)
+	}
+
+	fmt.Printf("Calling RTO API: %s %s %s\n", method, apiURL, string(jsonData))
+
+	req, err := http.NewRequest(method, apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create RTO request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call RTO API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read RTO response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("RTO API returned status %d: %s", resp.StatusCode, string(responseBody))
+	}
+
+	fmt.Printf("RTO API call successful: %s\n", string(responseBody))
+	return nil
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Manifest formats accepted by the RTO importer
+const (
+	RTOManifestCSV  = "csv"
+	RTOManifestJSON = "json"
+)
+
+// ErrUnknownManifestFormat is returned for a manifest format other than csv or json
+var ErrUnknownManifestFormat = errors.New("manifest format must be csv or json")
+
+// rtoManifestColumns are the fields every manifest row must carry
+var rtoManifestColumns = []string{"sub_order_num", "product_id", "catalog_id", "order_date", "pincode"}
+
+var pincodePattern = regexp.MustCompile(`^[1-9][0-9]{5}$`)
+
+// manifestField accepts both JSON strings and numbers, since couriers differ
+// in how they encode IDs and pincodes
+type manifestField string
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (f *manifestField) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*f = manifestField(text)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("expected a string or number, got %s", string(data))
+	}
+	*f = manifestField(number.String())
+	return nil
+}
+
+// RTOManifestRow is one returned unit as listed in a courier manifest
+type RTOManifestRow struct {
+	SubOrderNum manifestField `json:"sub_order_num"`
+	ProductID   manifestField `json:"product_id"`
+	CatalogID   manifestField `json:"catalog_id"`
+	OrderDate   manifestField `json:"order_date"`
+	Pincode     manifestField `json:"pincode"`
+}
+
+// RTOImportRow reports what happened to one manifest row. Row numbers are
+// 1-based and count data rows only.
+type RTOImportRow struct {
+	Row         int    `json:"row"`
+	SubOrderNum string `json:"sub_order_num,omitempty"`
+	Code        string `json:"code,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// RTOImportReport summarises an import run
+type RTOImportReport struct {
+	Source        string         `json:"source"`
+	Format        string         `json:"format"`
+	DryRun        bool           `json:"dry_run"`
+	TotalRows     int            `json:"total_rows"`
+	AcceptedCount int            `json:"accepted_count"`
+	RejectedCount int            `json:"rejected_count"`
+	Accepted      []RTOImportRow `json:"accepted"`
+	Rejected      []RTOImportRow `json:"rejected"`
+	StartedAt     time.Time      `json:"started_at"`
+	FinishedAt    time.Time      `json:"finished_at"`
+}
+
+// RTOImportService loads courier return manifests into the RTO store
+type RTOImportService struct {
+	db    *gorm.DB
+	store RTOStore
+}
+
+// NewRTOImportService creates a new RTO import service using the configured RTO store
+func NewRTOImportService() *RTOImportService {
+	return &RTOImportService{
+		db:    configs.DB,
+		store: NewRTOStore(),
+	}
+}
+
+// ParseRTOManifest reads the rows of a CSV or JSON manifest. CSV manifests
+// need a header row naming the columns; JSON manifests are an array of objects.
+func ParseRTOManifest(r io.Reader, format string) ([]RTOManifestRow, error) {
+	switch format {
+	case RTOManifestCSV:
+		return parseRTOManifestCSV(r)
+	case RTOManifestJSON:
+		var rows []RTOManifestRow
+		if err := json.NewDecoder(r).Decode(&rows); err != nil {
+			return nil, fmt.Errorf("invalid JSON manifest: %w", err)
+		}
+		return rows, nil
+	default:
+		return nil, ErrUnknownManifestFormat
+	}
+}
+
+// parseRTOManifestCSV maps CSV columns to manifest fields by header name
+func parseRTOManifestCSV(r io.Reader) ([]RTOManifestRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV manifest: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, column := range rtoManifestColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("invalid CSV manifest: missing column %q", column)
+		}
+	}
+
+	field := func(record []string, column string) manifestField {
+		if i := index[column]; i < len(record) {
+			return manifestField(record[i])
+		}
+		return ""
+	}
+
+	var rows []RTOManifestRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV manifest: %w", err)
+		}
+		rows = append(rows, RTOManifestRow{
+			SubOrderNum: field(record, "sub_order_num"),
+			ProductID:   field(record, "product_id"),
+			CatalogID:   field(record, "catalog_id"),
+			OrderDate:   field(record, "order_date"),
+			Pincode:     field(record, "pincode"),
+		})
+	}
+	return rows, nil
+}
+
+// Import validates the rows, drops sub order numbers that are repeated in
+// the manifest or already listed at any RTO code, maps each destination
+// pincode to the RTO code that serves it and adds the units to the RTO
+// store. With dryRun nothing is written.
+func (s *RTOImportService) Import(source, format string, rows []RTOManifestRow, dryRun bool) (*RTOImportReport, error) {
+	report := &RTOImportReport{
+		Source:    source,
+		Format:    format,
+		DryRun:    dryRun,
+		TotalRows: len(rows),
+		Accepted:  []RTOImportRow{},
+		Rejected:  []RTOImportRow{},
+		StartedAt: time.Now(),
+	}
+
+	rules, err := s.loadPincodeRules()
+	if err != nil {
+		return nil, err
+	}
+
+	listed, err := s.listedSubOrderNums(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]int, len(rows))
+	for i, row := range rows {
+		result := RTOImportRow{Row: i + 1, SubOrderNum: strings.TrimSpace(string(row.SubOrderNum))}
+		reject := func(reason string) {
+			result.Reason = reason
+			report.Rejected = append(report.Rejected, result)
+		}
+
+		product, err := validateManifestRow(row)
+		if err != nil {
+			reject(err.Error())
+			continue
+		}
+
+		if first, ok := seen[product.SubOrderNum]; ok {
+			reject(fmt.Sprintf("duplicate sub_order_num, first seen in row %d", first))
+			continue
+		}
+		seen[product.SubOrderNum] = result.Row
+
+		if listedAt, ok := listed[product.SubOrderNum]; ok {
+			reject(fmt.Sprintf("sub_order_num is already listed at RTO code %s", listedAt))
+			continue
+		}
+
+		pincode := strings.TrimSpace(string(row.Pincode))
+		code := codeForPincode(rules, pincode)
+		if code == "" {
+			reject(fmt.Sprintf("no RTO code serves pincode %s", pincode))
+			continue
+		}
+		result.Code = code
+
+		if !dryRun {
+			if err := s.store.Add(code, product); err != nil {
+				reject(fmt.Sprintf("failed to save: %v", err))
+				continue
+			}
+		}
+		report.Accepted = append(report.Accepted, result)
+	}
+
+	report.AcceptedCount = len(report.Accepted)
+	report.RejectedCount = len(report.Rejected)
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// validateManifestRow checks a row and converts it to an RTO unit
+func validateManifestRow(row RTOManifestRow) (models.RTOProduct, error) {
+	subOrderNum := strings.TrimSpace(string(row.SubOrderNum))
+	if subOrderNum == "" {
+		return models.RTOProduct{}, errors.New("sub_order_num is required")
+	}
+	if len(subOrderNum) > 50 {
+		return models.RTOProduct{}, errors.New("sub_order_num is longer than 50 characters")
+	}
+
+	productID, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(string(row.ProductID)), "s-"), 10, 64)
+	if err != nil || productID <= 0 {
+		return models.RTOProduct{}, fmt.Errorf("invalid product_id %q", row.ProductID)
+	}
+
+	catalogID, err := strconv.ParseInt(strings.TrimSpace(string(row.CatalogID)), 10, 64)
+	if err != nil || catalogID <= 0 {
+		return models.RTOProduct{}, fmt.Errorf("invalid catalog_id %q", row.CatalogID)
+	}
+
+	orderDate, err := parseManifestDate(strings.TrimSpace(string(row.OrderDate)))
+	if err != nil {
+		return models.RTOProduct{}, fmt.Errorf("invalid order_date %q, expected YYYY-MM-DD", row.OrderDate)
+	}
+	if orderDate.After(time.Now()) {
+		return models.RTOProduct{}, fmt.Errorf("order_date %q is in the future", row.OrderDate)
+	}
+
+	if !pincodePattern.MatchString(strings.TrimSpace(string(row.Pincode))) {
+		return models.RTOProduct{}, fmt.Errorf("invalid pincode %q", row.Pincode)
+	}
+
+	return models.RTOProduct{
+		RTOCount:    1,
+		CatalogID:   catalogID,
+		OrderDate:   orderDate.Format("2006-01-02"),
+		ProductID:   productID,
+		SubOrderNum: subOrderNum,
+	}, nil
+}
+
+// parseManifestDate accepts plain dates and RFC 3339 timestamps
+func parseManifestDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// loadPincodeRules returns the RTO codes serving each pincode or pincode
+// prefix, each list in code order
+func (s *RTOImportService) loadPincodeRules() (map[string][]string, error) {
+	var serviceable []models.ServiceablePincode
+	if err := s.db.Order("code").Find(&serviceable).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	rules := make(map[string][]string)
+	for _, rule := range serviceable {
+		rules[rule.Pincode] = append(rules[rule.Pincode], rule.Code)
+	}
+	return rules, nil
+}
+
+// listedSubOrderNums returns the sub order numbers of the units already held
+// at the codes named by the pincode rules, mapped to the code holding each
+func (s *RTOImportService) listedSubOrderNums(rules map[string][]string) (map[string]string, error) {
+	listed := make(map[string]string)
+	fetched := make(map[string]bool)
+	for _, codes := range rules {
+		for _, code := range codes {
+			if fetched[code] {
+				continue
+			}
+			fetched[code] = true
+
+			units, err := s.store.FetchByCode(code)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the RTO list of code %s: %w", code, err)
+			}
+			for _, unit := range units {
+				if unit.SubOrderNum != "" {
+					listed[unit.SubOrderNum] = code
+				}
+			}
+		}
+	}
+	return listed, nil
+}
+
+// codeForPincode returns the code with the most specific rule for a pincode.
+// When several codes share that rule the first in code order is used.
+func codeForPincode(rules map[string][]string, pincode string) string {
+	for length := len(pincode); length > 0; length-- {
+		if codes := rules[pincode[:length]]; len(codes) > 0 {
+			return codes[0]
+		}
+	}
+	return ""
+}
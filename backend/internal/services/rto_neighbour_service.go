@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// RTONeighbourService finds the RTO codes near a user using the
+// rto_code_locations table
+type RTONeighbourService struct {
+	db            *gorm.DB
+	maxNeighbours int
+}
+
+// NewRTONeighbourService creates a new RTO neighbour service. At most
+// RTO_MAX_NEIGHBOURS codes (5) are returned for each tier.
+func NewRTONeighbourService() *RTONeighbourService {
+	return &RTONeighbourService{
+		db:            configs.DB,
+		maxNeighbours: getEnvInt("RTO_MAX_NEIGHBOURS", 5),
+	}
+}
+
+// CityNeighbours returns the other codes in the user's city, nearest first
+func (s *RTONeighbourService) CityNeighbours(userMapping models.UserMapping) ([]string, error) {
+	origin, err := s.locate(userMapping)
+	if err != nil || origin.City == "" {
+		return nil, err
+	}
+
+	var candidates []models.RTOCodeLocation
+	err = s.db.Where("city = ? AND code <> ?", origin.City, origin.Code).Find(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	return s.nearest(origin, candidates), nil
+}
+
+// StateCodes returns the codes elsewhere in the user's state, nearest first
+func (s *RTONeighbourService) StateCodes(userMapping models.UserMapping) ([]string, error) {
+	origin, err := s.locate(userMapping)
+	if err != nil || origin.State == "" {
+		return nil, err
+	}
+
+	var candidates []models.RTOCodeLocation
+	query := s.db.Where("state = ? AND code <> ?", origin.State, origin.Code)
+	if origin.City != "" {
+		// Codes in the same city were already tried by the city tier
+		query = query.Where("city <> ?", origin.City)
+	}
+	if err := query.Find(&candidates).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	return s.nearest(origin, candidates), nil
+}
+
+// locate returns where the user is: the location of their code, with the
+// city and state from their mapping taking precedence
+func (s *RTONeighbourService) locate(userMapping models.UserMapping) (models.RTOCodeLocation, error) {
+	origin := models.RTOCodeLocation{Code: userMapping.Code}
+
+	if userMapping.Code != "" {
+		var location models.RTOCodeLocation
+		result := s.db.Where("code = ?", userMapping.Code).Limit(1).Find(&location)
+		if result.Error != nil {
+			return origin, fmt.Errorf("database error: %v", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			origin = location
+		}
+	}
+
+	if city := strings.TrimSpace(userMapping.City); city != "" {
+		origin.City = city
+	}
+	if state := strings.TrimSpace(userMapping.State); state != "" {
+		origin.State = state
+	}
+	return origin, nil
+}
+
+// nearest orders candidate codes by distance from the origin and keeps the
+// closest. Codes without coordinates, or when the origin has none, come
+// after the located ones in code order.
+func (s *RTONeighbourService) nearest(origin models.RTOCodeLocation, candidates []models.RTOCodeLocation) []string {
+	distance := func(location models.RTOCodeLocation) float64 {
+		if origin.Latitude == nil || origin.Longitude == nil || location.Latitude == nil || location.Longitude == nil {
+			return math.Inf(1)
+		}
+		return haversineKm(*origin.Latitude, *origin.Longitude, *location.Latitude, *location.Longitude)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		di, dj := distance(candidates[i]), distance(candidates[j])
+		if di != dj {
+			return di < dj
+		}
+		return candidates[i].Code < candidates[j].Code
+	})
+
+	codes := make([]string, 0, min(len(candidates), s.maxNeighbours))
+	for _, candidate := range candidates {
+		if len(codes) == s.maxNeighbours {
+			break
+		}
+		codes = append(codes, candidate.Code)
+	}
+	return codes
+}
+
+// haversineKm returns the great-circle distance between two points in kilometres
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
@@ -1,130 +1,148 @@
 package services
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"meesho-clone/internal/models"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrRTOUnitNotFound is returned when the RTO list of a code does not hold the requested unit
+var ErrRTOUnitNotFound = errors.New("item is no longer available at this RTO location")
+
 // RTOService handles RTO-related operations
 type RTOService struct {
-	rtoAPIURL  string
-	httpClient *http.Client
-}
-
-// RTOItem represents a single RTO item in the response
-type RTOItem struct {
-	CatalogID int64  `json:"catalog_id"`
-	OrderDate string `json:"order_date"`
-	ProductID int64  `json:"product_id"`
-	RTOCount  int    `json:"rto_count"`
+	store RTOStore
 }
 
-// RTOResponse represents the response from the RTO API
-type RTOResponse struct {
-	Code       string             `json:"code"`
-	RTOList    map[string]RTOItem `json:"rto_list"`
-	Success    bool               `json:"success"`
-	TotalItems int                `json:"total_items"`
+// RTODropRequest represents the request to the external RTO delete and add APIs
+type RTODropRequest struct {
+	Code        string `json:"code"`
+	ProductID   int    `json:"product_id"`
+	CatalogID   int    `json:"catalog_id"`
+	SubOrderNum string `json:"sub_order_num,omitempty"`
+	OrderDate   string `json:"order_date,omitempty"`
 }
 
-// NewRTOService creates a new RTO service
+// NewRTOService creates a new RTO service using the configured RTO store
 func NewRTOService() *RTOService {
-	// Get RTO API URL from environment variable with fallback
-	rtoAPIURL := os.Getenv("RTO_API_URL")
-	if rtoAPIURL == "" {
-		rtoAPIURL = "http://localhost:3001/rto/fetch"
-	}
-
 	return &RTOService{
-		rtoAPIURL: rtoAPIURL,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		store: NewRTOStore(),
 	}
 }
 
-// GetCatalogIDsFromRTO fetches catalog IDs from RTO API based on user's code
-func (s *RTOService) GetCatalogIDsFromRTO(userCode string) ([]string, error) {
-	// Prepare the API URL
-	apiURL := fmt.Sprintf("%s/%s", s.rtoAPIURL, userCode)
-
-	fmt.Printf("Calling RTO API: %s\n", apiURL)
-
-	// Create HTTP request
-	req, err := http.NewRequest("GET", apiURL, nil)
+// GetRTOUnits fetches the RTO units held at a code, tagging each with it
+func (s *RTOService) GetRTOUnits(userCode string) (models.RTOProducts, error) {
+	units, err := s.store.FetchByCode(userCode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create RTO request: %w", err)
+		return nil, err
 	}
+	for i := range units {
+		units[i].Code = userCode
+	}
+
+	fmt.Printf("Successfully got %d RTO units from RTO store for code '%s'\n", len(units), userCode)
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
+	return units, nil
+}
 
-	// Make the request
-	resp, err := s.httpClient.Do(req)
+// GetRTOUnitsWithFallback calls the RTO store with fallback to empty list
+func (s *RTOService) GetRTOUnitsWithFallback(userCode string) models.RTOProducts {
+	units, err := s.GetRTOUnits(userCode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call RTO API: %w", err)
+		// Log the error but return empty list as fallback
+		fmt.Printf("Warning: Failed to get RTO units from RTO store: %v. Using empty list.\n", err)
+		return models.RTOProducts{}
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read RTO response: %w", err)
+	// If the RTO store returns empty result, return empty list
+	if len(units) == 0 {
+		fmt.Printf("Warning: RTO store returned empty result. Using empty list.\n")
+		return models.RTOProducts{}
 	}
 
-	// Check HTTP status
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("RTO API returned status %d: %s", resp.StatusCode, string(responseBody))
+	return units
+}
+
+// FindUnits returns the units of a product held at a code, narrowed to a
+// single sub order number when one is given
+func (s *RTOService) FindUnits(code, productID, subOrderNum string) (models.RTOProducts, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(productID, "s-"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid product %s", ErrRTOUnitNotFound, productID)
 	}
 
-	// Parse response
-	var rtoResponse RTOResponse
-	if err := json.Unmarshal(responseBody, &rtoResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal RTO response: %w", err)
+	units, err := s.store.FetchByCode(code)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if the response indicates success
-	if !rtoResponse.Success {
-		return nil, fmt.Errorf("RTO API returned error: success field is false")
+	var matches models.RTOProducts
+	for _, unit := range units {
+		if isRTOUnit(unit, id, subOrderNum) {
+			unit.Code = code
+			matches = append(matches, unit)
+		}
 	}
+	return matches, nil
+}
 
-	// Extract catalog IDs from the RTO list
+// RTOCatalogIDs returns the catalog IDs of the given RTO units
+func RTOCatalogIDs(units models.RTOProducts) []string {
 	var catalogIDs []string
-	for _, rtoItem := range rtoResponse.RTOList {
-		catalogIDs = append(catalogIDs, fmt.Sprintf("%d", rtoItem.CatalogID))
+	for _, unit := range units {
+		catalogIDs = append(catalogIDs, fmt.Sprintf("%d", unit.CatalogID))
 	}
+	return catalogIDs
+}
 
-	fmt.Printf("Successfully got %d catalog IDs from RTO API for code '%s'\n", len(catalogIDs), userCode)
-
-	return catalogIDs, nil
+// DropProduct removes a sold unit from the RTO list of a code
+func (s *RTOService) DropProduct(dropRequest RTODropRequest) error {
+	return s.store.DropUnit(dropRequest.Code, int64(dropRequest.ProductID), dropRequest.SubOrderNum)
 }
 
-// GetCatalogIDsFromRTOWithFallback calls the RTO API with fallback to empty list
-func (s *RTOService) GetCatalogIDsFromRTOWithFallback(userCode string) []string {
-	catalogIDs, err := s.GetCatalogIDsFromRTO(userCode)
-	if err != nil {
-		// Log the error but return empty list as fallback
-		fmt.Printf("Warning: Failed to get catalog IDs from RTO API: %v. Using empty list.\n", err)
-		return []string{}
+// RestoreProduct puts a unit back into the RTO list of a code, keeping its
+// original order date so it does not start ageing again
+func (s *RTOService) RestoreProduct(restoreRequest RTODropRequest) error {
+	orderDate := restoreRequest.OrderDate
+	if orderDate == "" {
+		orderDate = time.Now().Format("2006-01-02")
 	}
 
-	// If RTO API returns empty result, return empty list
-	if len(catalogIDs) == 0 {
-		fmt.Printf("Warning: RTO API returned empty result. Using empty list.\n")
-		return []string{}
+	return s.store.Add(restoreRequest.Code, models.RTOProduct{
+		RTOCount:    1,
+		CatalogID:   int64(restoreRequest.CatalogID),
+		ProductID:   int64(restoreRequest.ProductID),
+		OrderDate:   orderDate,
+		SubOrderNum: restoreRequest.SubOrderNum,
+	})
+}
+
+// ParseRTOProductID converts a product ID string to the integer the RTO API expects
+func ParseRTOProductID(productID string) int {
+	// Remove 's-' prefix if present
+	if len(productID) > 2 && productID[:2] == "s-" {
+		productID = productID[2:]
 	}
 
-	return catalogIDs
+	// Try to parse as integer, return fallback if failed
+	var result int
+	_, err := fmt.Sscanf(productID, "%d", &result)
+	if err != nil {
+		return 67890 // Fallback value
+	}
+	return result
 }
 
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+// ParseRTOCatalogID converts a catalog ID string to the integer the RTO API expects
+func ParseRTOCatalogID(catalogID string) int {
+	// Try to parse as integer, return fallback if failed
+	var result int
+	_, err := fmt.Sscanf(catalogID, "%d", &result)
+	if err != nil {
+		return 12345 // Fallback value
 	}
-	return b
+	return result
 }
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"os"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RTOStore reads and updates the list of RTO units held at each RTO code
+type RTOStore interface {
+	// FetchByCode returns the units held at a code
+	FetchByCode(code string) (models.RTOProducts, error)
+
+	// Add puts a unit into the list of a code, replacing the entry of the
+	// same unit if it is already there
+	Add(code string, product models.RTOProduct) error
+
+	// DropUnit removes a single unit from the list of a code. The unit is
+	// matched by sub order number; without one, a single unit of the
+	// product is removed.
+	DropUnit(code string, productID int64, subOrderNum string) error
+}
+
+// NewRTOStore returns the RTO store selected by RTO_STORE: "http" (default)
+// calls the external RTO service, "db" uses the rto_list table directly
+func NewRTOStore() RTOStore {
+	switch backend := os.Getenv("RTO_STORE"); backend {
+	case "", "http":
+		return NewHTTPRTOStore()
+	case "db":
+		return NewDBRTOStore()
+	default:
+		fmt.Printf("Warning: Unknown RTO_STORE %q. Using the HTTP RTO store.\n", backend)
+		return NewHTTPRTOStore()
+	}
+}
+
+// DBRTOStore keeps RTO lists in the rto_list table. A code normally has one
+// row; when older data has several, their products are read together.
+type DBRTOStore struct {
+	db *gorm.DB
+}
+
+// NewDBRTOStore creates an RTO store backed by the rto_list table
+func NewDBRTOStore() *DBRTOStore {
+	return &DBRTOStore{
+		db: configs.DB,
+	}
+}
+
+// FetchByCode implements RTOStore
+func (s *DBRTOStore) FetchByCode(code string) (models.RTOProducts, error) {
+	var rows []models.RTOList
+	if err := s.db.Where("code = ?", code).Order("id").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	products := models.RTOProducts{}
+	for _, row := range rows {
+		products = append(products, row.Products...)
+	}
+	return products, nil
+}
+
+// Add implements RTOStore. A unit already in the list is updated in place
+// rather than added twice, so replaying an add is harmless.
+func (s *DBRTOStore) Add(code string, product models.RTOProduct) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var rows []models.RTOList
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).Order("id").Find(&rows).Error
+		if err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+
+		for _, row := range rows {
+			for i, existing := range row.Products {
+				if !sameRTOUnit(existing, product) {
+					continue
+				}
+				if existing == product {
+					return nil
+				}
+				row.Products[i] = product
+				if err := tx.Model(&row).Update("products", row.Products).Error; err != nil {
+					return fmt.Errorf("failed to update RTO list: %w", err)
+				}
+				return nil
+			}
+		}
+
+		if len(rows) == 0 {
+			row := models.RTOList{Code: code, Products: models.RTOProducts{product}}
+			if err := tx.Create(&row).Error; err != nil {
+				return fmt.Errorf("failed to create RTO list: %w", err)
+			}
+			return nil
+		}
+
+		row := rows[0]
+		row.Products = append(row.Products, product)
+		if err := tx.Model(&row).Update("products", row.Products).Error; err != nil {
+			return fmt.Errorf("failed to update RTO list: %w", err)
+		}
+		return nil
+	})
+}
+
+// DropUnit implements RTOStore. Dropping a unit that is not in the list
+// succeeds, so replaying a drop is harmless.
+func (s *DBRTOStore) DropUnit(code string, productID int64, subOrderNum string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var rows []models.RTOList
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).Order("id").Find(&rows).Error
+		if err != nil {
+			return fmt.Errorf("database error: %v", err)
+		}
+
+		for _, row := range rows {
+			for i, product := range row.Products {
+				if !isRTOUnit(product, productID, subOrderNum) {
+					continue
+				}
+				kept := append(row.Products[:i:i], row.Products[i+1:]...)
+				if err := tx.Model(&row).Update("products", kept).Error; err != nil {
+					return fmt.Errorf("failed to update RTO list: %w", err)
+				}
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// isRTOUnit reports whether an entry is the unit with the given product and
+// sub order number. Without a sub order number any unit of the product matches.
+func isRTOUnit(product models.RTOProduct, productID int64, subOrderNum string) bool {
+	if product.ProductID != productID {
+		return false
+	}
+	return subOrderNum == "" || product.SubOrderNum == subOrderNum
+}
+
+// sameRTOUnit reports whether two entries describe the same unit. Units are
+// identified by sub order number when both have one, otherwise by product.
+func sameRTOUnit(a, b models.RTOProduct) bool {
+	if a.SubOrderNum != "" && b.SubOrderNum != "" {
+		return a.SubOrderNum == b.SubOrderNum
+	}
+	return a.ProductID == b.ProductID && a.CatalogID == b.CatalogID
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSessionNotFound is returned when no session matches the request
+	ErrSessionNotFound = errors.New("session not found")
+
+	// ErrSessionRevoked is returned when a session has been logged out
+	ErrSessionRevoked = errors.New("session has been revoked")
+)
+
+// SessionService manages login sessions and their revocation
+type SessionService struct {
+	db *gorm.DB
+
+	// touchInterval limits how often last_seen_at is written per session
+	touchInterval time.Duration
+}
+
+// NewSessionService creates a new session service
+func NewSessionService() *SessionService {
+	return &SessionService{
+		db:            configs.DB,
+		touchInterval: getEnvDuration("SESSION_TOUCH_INTERVAL", time.Minute),
+	}
+}
+
+// createSession records a new session inside the caller's transaction
+func (s *SessionService) createSession(tx *gorm.DB, sessionID, userID string, info models.SessionInfo) error {
+	session := models.Session{
+		SessionID:  sessionID,
+		UserID:     userID,
+		DeviceInfo: truncateText(info.DeviceInfo, 255),
+		IPAddress:  info.IPAddress,
+		LastSeenAt: time.Now(),
+	}
+	if err := tx.Create(&session).Error; err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// ValidateSession checks that a session is still active and records the activity
+func (s *SessionService) ValidateSession(sessionID, userID, ip string) (*models.Session, error) {
+	var session models.Session
+	err := s.db.Where("session_id = ? AND user_id = ?", sessionID, userID).First(&session).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	if session.RevokedAt != nil {
+		return nil, ErrSessionRevoked
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) >= s.touchInterval || session.IPAddress != ip {
+		err := s.db.Model(&session).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip_address":   ip,
+		}).Error
+		if err != nil {
+			fmt.Printf("Warning: Failed to update last seen for session %s: %v\n", sessionID, err)
+		}
+	}
+
+	return &session, nil
+}
+
+// ListSessions returns the active sessions of a user, most recently used first
+func (s *SessionService) ListSessions(userID string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession logs out one session of a user
+func (s *SessionService) RevokeSession(userID, sessionID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Session{}).
+			Where("session_id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to revoke session: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrSessionNotFound
+		}
+		return s.revokeRefreshTokens(tx, "family_id = ?", sessionID)
+	})
+}
+
+// RevokeSessionByID revokes a session regardless of its owner, e.g. when a
+// refresh token of the session was reused
+func (s *SessionService) RevokeSessionByID(sessionID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Session{}).
+			Where("session_id = ? AND revoked_at IS NULL", sessionID).
+			Update("revoked_at", time.Now()).Error
+		if err != nil {
+			return fmt.Errorf("failed to revoke session: %w", err)
+		}
+		return s.revokeRefreshTokens(tx, "family_id = ?", sessionID)
+	})
+}
+
+// RevokeAllSessions logs a user out everywhere
+func (s *SessionService) RevokeAllSessions(userID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.revokeAllSessions(tx, userID)
+	})
+}
+
+// revokeAllSessions revokes every session of a user inside the caller's transaction
+func (s *SessionService) revokeAllSessions(tx *gorm.DB, userID string) error {
+	err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return s.revokeRefreshTokens(tx, "user_id = ?", userID)
+}
+
+// revokeRefreshTokens revokes the refresh tokens matching the condition
+func (s *SessionService) revokeRefreshTokens(tx *gorm.DB, condition string, value string) error {
+	err := tx.Model(&models.RefreshToken{}).
+		Where(condition+" AND revoked_at IS NULL", value).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"meesho-clone/configs"
+	"meesho-clone/internal/pii"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// SMSSender delivers text messages to phone numbers
+type SMSSender interface {
+	Send(phoneNumber, message string) error
+}
+
+// NewSMSSender returns the sender configured by SMS_SENDER. "http" sends
+// through the SMS gateway at SMS_API_URL. The local stand-ins "console" and
+// "file" write the codes out instead, so they are only available with
+// APP_ENV=development, where console is also the default.
+func NewSMSSender() (SMSSender, error) {
+	name := os.Getenv("SMS_SENDER")
+	switch name {
+	case "http":
+		return NewHTTPSMSSender()
+	case "", "console", "file":
+		if !configs.IsDevelopment() {
+			if name == "" {
+				return nil, errors.New("SMS_SENDER is not set")
+			}
+			return nil, fmt.Errorf("the %s SMS sender is only available with APP_ENV=development", name)
+		}
+		if name != "file" {
+			return &ConsoleSMSSender{}, nil
+		}
+		path := os.Getenv("SMS_OUTBOX_FILE")
+		if path == "" {
+			path = "sms_outbox.log"
+		}
+		return NewFileSMSSender(path), nil
+	default:
+		return nil, fmt.Errorf("unknown SMS_SENDER %q", name)
+	}
+}
+
+// HTTPSMSSender posts messages to an SMS gateway
+type HTTPSMSSender struct {
+	apiURL     string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewHTTPSMSSender creates a sender for the gateway at SMS_API_URL,
+// authenticated with SMS_API_KEY
+func NewHTTPSMSSender() (*HTTPSMSSender, error) {
+	apiURL := os.Getenv("SMS_API_URL")
+	if apiURL == "" {
+		return nil, errors.New("SMS_API_URL must be set for the http SMS sender")
+	}
+
+	return &HTTPSMSSender{
+		apiURL: apiURL,
+		apiKey: os.Getenv("SMS_API_KEY"),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}, nil
+}
+
+// Send posts the message to the gateway
+func (s *HTTPSMSSender) Send(phoneNumber, message string) error {
+	body, err := json.Marshal(map[string]string{
+		"to":      phoneNumber,
+		"message": message,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal SMS: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", s.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS gateway returned status %d: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
+
+// ConsoleSMSSender prints messages to stdout instead of sending them.
+// Only the last digits of the phone number are printed.
+type ConsoleSMSSender struct{}
+
+// Send prints the message
+func (s *ConsoleSMSSender) Send(phoneNumber, message string) error {
+	fmt.Printf("SMS to %s: %s\n", pii.MaskPhone(phoneNumber), message)
+	return nil
+}
+
+// FileSMSSender appends messages to a file instead of sending them.
+// Only the last digits of the phone number are written.
+type FileSMSSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSMSSender creates a sender that writes to the given file
+func NewFileSMSSender(path string) *FileSMSSender {
+	return &FileSMSSender{path: path}
+}
+
+// Send appends the message to the outbox file
+func (s *FileSMSSender) Send(phoneNumber, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open SMS outbox file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), pii.MaskPhone(phoneNumber), message)
+	if err != nil {
+		return fmt.Errorf("failed to write SMS outbox file: %w", err)
+	}
+	return nil
+}
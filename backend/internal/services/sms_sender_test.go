@@ -0,0 +1,34 @@
+package services
+
+import "testing"
+
+func TestNewSMSSender(t *testing.T) {
+	cases := []struct {
+		name    string
+		sender  string
+		appEnv  string
+		apiURL  string
+		wantErr bool
+	}{
+		{name: "unset outside development", wantErr: true},
+		{name: "unset in development", appEnv: "development"},
+		{name: "console outside development", sender: "console", wantErr: true},
+		{name: "file outside development", sender: "file", wantErr: true},
+		{name: "file in development", sender: "file", appEnv: "development"},
+		{name: "unknown sender", sender: "pigeon", appEnv: "development", wantErr: true},
+		{name: "http without url", sender: "http", wantErr: true},
+		{name: "http", sender: "http", apiURL: "https://sms.example.com/send"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("SMS_SENDER", tc.sender)
+			t.Setenv("APP_ENV", tc.appEnv)
+			t.Setenv("SMS_API_URL", tc.apiURL)
+
+			_, err := NewSMSSender()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewSMSSender() = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
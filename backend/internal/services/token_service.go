@@ -0,0 +1,292 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+var (
+	// ErrInvalidToken is returned when a token is malformed, has a bad signature or has expired
+	ErrInvalidToken = errors.New("invalid or expired token")
+
+	// ErrTokenRevoked is returned when a refresh token was revoked or has already been used
+	ErrTokenRevoked = errors.New("token has been revoked")
+)
+
+// jwtHeader is the fixed header of every token we issue
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// TokenClaims are the claims carried by access and refresh tokens
+type TokenClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	TokenID   string `json:"jti"`
+	FamilyID  string `json:"fam"`
+	Type      string `json:"typ"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenService issues and verifies HS256-signed JWT access and refresh tokens
+type TokenService struct {
+	db         *gorm.DB
+	sessions   *SessionService
+	secret     []byte
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenService creates a new token service. JWT_SECRET must be set
+// outside development so that tokens survive restarts and are accepted by
+// every instance.
+func NewTokenService() (*TokenService, error) {
+	secret := []byte(os.Getenv("JWT_SECRET"))
+	if len(secret) == 0 {
+		if !configs.IsDevelopment() {
+			return nil, errors.New("JWT_SECRET must be set unless APP_ENV=development")
+		}
+		fmt.Printf("Warning: JWT_SECRET not set. Using a random key; tokens will not survive a restart.\n")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	}
+
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = "meesho-clone"
+	}
+
+	return &TokenService{
+		db:         configs.DB,
+		sessions:   NewSessionService(),
+		secret:     secret,
+		issuer:     issuer,
+		accessTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		refreshTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+	}, nil
+}
+
+// IssueTokens starts a new session for the user and returns its first token pair.
+// The session ID doubles as the token family ID.
+func (s *TokenService) IssueTokens(userID string, info models.SessionInfo) (*models.TokenPair, error) {
+	sessionID := generateTokenID("sess")
+
+	var pair *models.TokenPair
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.sessions.createSession(tx, sessionID, userID, info); err != nil {
+			return err
+		}
+
+		var err error
+		pair, err = s.issuePair(tx, userID, sessionID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// Refresh rotates a refresh token: the presented token is marked used and a
+// new pair in the same family is returned. Presenting an already used token
+// means it was leaked, so the whole session is logged out.
+func (s *TokenService) Refresh(refreshToken string) (*models.TokenPair, error) {
+	claims, err := s.parse(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	var pair *models.TokenPair
+	var reused bool
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var stored models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_id = ?", claims.TokenID).
+			First(&stored).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrInvalidToken
+			}
+			return fmt.Errorf("database error: %v", err)
+		}
+
+		if stored.RevokedAt != nil {
+			return ErrTokenRevoked
+		}
+		if stored.RotatedAt != nil {
+			reused = true
+			return ErrTokenRevoked
+		}
+
+		pair, err = s.issuePair(tx, stored.UserID, stored.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		next, err := s.parse(pair.RefreshToken, tokenTypeRefresh)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		err = tx.Model(&stored).Updates(map[string]interface{}{
+			"rotated_at":  now,
+			"replaced_by": next.TokenID,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to rotate refresh token: %w", err)
+		}
+		return nil
+	})
+	if reused {
+		fmt.Printf("Warning: Refresh token %s reused, revoking family %s\n", claims.TokenID, claims.FamilyID)
+		if revokeErr := s.sessions.RevokeSessionByID(claims.FamilyID); revokeErr != nil {
+			fmt.Printf("Warning: Failed to revoke token family %s: %v\n", claims.FamilyID, revokeErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// Revoke ends the session of the given refresh token, logging that login out
+func (s *TokenService) Revoke(refreshToken string) error {
+	claims, err := s.parse(refreshToken, tokenTypeRefresh)
+	if err != nil {
+		return err
+	}
+	return s.sessions.RevokeSessionByID(claims.FamilyID)
+}
+
+// ParseAccessToken verifies an access token and returns its claims
+func (s *TokenService) ParseAccessToken(token string) (*TokenClaims, error) {
+	return s.parse(token, tokenTypeAccess)
+}
+
+// issuePair signs a new access and refresh token and records the refresh token
+func (s *TokenService) issuePair(tx *gorm.DB, userID, familyID string) (*models.TokenPair, error) {
+	now := time.Now()
+
+	access := TokenClaims{
+		Issuer:    s.issuer,
+		Subject:   userID,
+		TokenID:   generateTokenID("at"),
+		FamilyID:  familyID,
+		Type:      tokenTypeAccess,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.accessTTL).Unix(),
+	}
+	refresh := TokenClaims{
+		Issuer:    s.issuer,
+		Subject:   userID,
+		TokenID:   generateTokenID("rt"),
+		FamilyID:  familyID,
+		Type:      tokenTypeRefresh,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.refreshTTL).Unix(),
+	}
+
+	accessToken, err := s.sign(access)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.sign(refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := models.RefreshToken{
+		TokenID:   refresh.TokenID,
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: time.Unix(refresh.ExpiresAt, 0),
+	}
+	if err := tx.Create(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &models.TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  time.Unix(access.ExpiresAt, 0),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: time.Unix(refresh.ExpiresAt, 0),
+		TokenType:        "Bearer",
+	}, nil
+}
+
+// sign encodes and signs the claims as a compact JWT
+func (s *TokenService) sign(claims TokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token claims: %w", err)
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.signature(unsigned), nil
+}
+
+// parse verifies the signature, expiry, issuer and type of a token
+func (s *TokenService) parse(token, tokenType string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+
+	expected := s.signature(parts[0] + "." + parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims TokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Type != tokenType || claims.Issuer != s.issuer || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+
+	return &claims, nil
+}
+
+// signature returns the base64url HMAC-SHA256 of the signing input
+func (s *TokenService) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// generateTokenID generates a unique token identifier with the given prefix
+func generateTokenID(prefix string) string {
+	bytes := make([]byte, 12)
+	rand.Read(bytes)
+	return prefix + "_" + hex.EncodeToString(bytes)
+}
@@ -0,0 +1,16 @@
+package services
+
+import "testing"
+
+func TestNewTokenServiceRequiresSecretOutsideDevelopment(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("APP_ENV", "")
+	if _, err := NewTokenService(); err == nil {
+		t.Fatal("NewTokenService without JWT_SECRET outside development should fail")
+	}
+
+	t.Setenv("APP_ENV", "development")
+	if _, err := NewTokenService(); err != nil {
+		t.Fatalf("NewTokenService in development: %v", err)
+	}
+}
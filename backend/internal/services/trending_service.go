@@ -0,0 +1,513 @@
+package services
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"meesho-clone/configs"
+	"meesho-clone/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// trendingSnapshotJSON is the trending list served before the trending job
+// has computed one, e.g. on a fresh database
+//
+//go:embed trending_snapshot.json
+var trendingSnapshotJSON []byte
+
+// An order counts for more than a click when scoring catalogs
+const (
+	trendingOrderWeight = 5.0
+	trendingClickWeight = 1.0
+)
+
+// trendingUserCategories is how many of a user's recent categories feed the fallback
+const trendingUserCategories = 3
+
+// TrendingService computes the trending catalogs used when a user has no
+// RTO units nearby, and applies the pins and blocklist set by staff
+type TrendingService struct {
+	db       *gorm.DB
+	interval time.Duration
+	window   time.Duration
+	topN     int
+}
+
+// TrendingSnapshot is the layout of trending_snapshot.json
+type TrendingSnapshot struct {
+	CatalogIDs []string `json:"catalog_ids"`
+}
+
+// trendingScope identifies one trending list
+type trendingScope struct {
+	scope string
+	value string
+}
+
+// catalogEventCount is the number of events of a catalog in a city
+type catalogEventCount struct {
+	CatalogID string
+	City      string
+	Events    float64
+}
+
+// NewTrendingService creates a new trending service
+func NewTrendingService() *TrendingService {
+	return &TrendingService{
+		db:       configs.DB,
+		interval: getEnvDuration("TRENDING_INTERVAL", time.Hour),
+		window:   getEnvDuration("TRENDING_WINDOW", 7*24*time.Hour),
+		topN:     getEnvInt("TRENDING_TOP_N", 100),
+	}
+}
+
+// StartTrendingJob recomputes the trending lists until the context is cancelled
+func (s *TrendingService) StartTrendingJob(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		computed, err := s.Compute()
+		if err != nil {
+			fmt.Printf("Warning: Trending catalog job failed: %v\n", err)
+		} else if computed > 0 {
+			fmt.Printf("Computed %d trending catalog entries\n", computed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecordClick records a user opening a catalog, tagged with the user's city
+func (s *TrendingService) RecordClick(userID, catalogID string) error {
+	var userMapping models.UserMapping
+	if err := s.db.Where("user_id = ?", userID).Limit(1).Find(&userMapping).Error; err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+
+	event := models.CatalogClickEvent{
+		UserID:    userID,
+		CatalogID: catalogID,
+		City:      userMapping.City,
+	}
+	if err := s.db.Create(&event).Error; err != nil {
+		return fmt.Errorf("failed to record catalog click: %w", err)
+	}
+	return nil
+}
+
+// Compute scores catalogs by their orders and clicks within the window and
+// replaces the trending lists with the top catalogs globally, per city and
+// per category. The previous lists are kept when there were no events.
+func (s *TrendingService) Compute() (int, error) {
+	since := time.Now().Add(-s.window)
+
+	var orderCounts []catalogEventCount
+	err := s.db.Table("order_items").
+		Select("order_items.catalog_id AS catalog_id, COALESCE(user_cities.city, '') AS city, SUM(order_items.quantity) AS events").
+		Joins("JOIN orders ON orders.order_id = order_items.order_id").
+		Joins("LEFT JOIN (SELECT user_id, MAX(city) AS city FROM user_mapping GROUP BY user_id) AS user_cities ON user_cities.user_id = orders.user_id").
+		Where("orders.created_at >= ? AND orders.status <> ? AND order_items.catalog_id <> ''", since, models.OrderStatusCancelled).
+		Group("order_items.catalog_id, user_cities.city").
+		Scan(&orderCounts).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	var clickCounts []catalogEventCount
+	err = s.db.Model(&models.CatalogClickEvent{}).
+		Select("catalog_id, city, COUNT(*) AS events").
+		Where("created_at >= ?", since).
+		Group("catalog_id, city").
+		Scan(&clickCounts).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	blocked, err := s.blockedCatalogs()
+	if err != nil {
+		return 0, err
+	}
+
+	scores := make(map[trendingScope]map[string]float64)
+	addScore := func(scope trendingScope, catalogID string, score float64) {
+		if scores[scope] == nil {
+			scores[scope] = make(map[string]float64)
+		}
+		scores[scope][catalogID] += score
+	}
+
+	cityScores := func(counts []catalogEventCount, weight float64) {
+		for _, count := range counts {
+			if blocked[count.CatalogID] {
+				continue
+			}
+			score := count.Events * weight
+			addScore(trendingScope{models.TrendingScopeGlobal, ""}, count.CatalogID, score)
+			if city := trendingScopeValue(count.City); city != "" {
+				addScore(trendingScope{models.TrendingScopeCity, city}, count.CatalogID, score)
+			}
+		}
+	}
+	cityScores(orderCounts, trendingOrderWeight)
+	cityScores(clickCounts, trendingClickWeight)
+
+	global := scores[trendingScope{models.TrendingScopeGlobal, ""}]
+	if len(global) == 0 {
+		return 0, nil
+	}
+
+	categories, err := s.catalogCategories(global)
+	if err != nil {
+		return 0, err
+	}
+	for catalogID, score := range global {
+		if category := trendingScopeValue(categories[catalogID]); category != "" {
+			addScore(trendingScope{models.TrendingScopeCategory, category}, catalogID, score)
+		}
+	}
+
+	computedAt := time.Now()
+	var rows []models.TrendingCatalog
+	for scope, catalogScores := range scores {
+		for position, catalogID := range s.top(catalogScores) {
+			rows = append(rows, models.TrendingCatalog{
+				Scope:      scope.scope,
+				ScopeValue: scope.value,
+				CatalogID:  catalogID,
+				Position:   position + 1,
+				Score:      catalogScores[catalogID],
+				ComputedAt: computedAt,
+			})
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.TrendingCatalog{}).Error; err != nil {
+			return fmt.Errorf("failed to clear trending catalogs: %w", err)
+		}
+		if err := tx.CreateInBatches(&rows, 500).Error; err != nil {
+			return fmt.Errorf("failed to save trending catalogs: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// top returns the highest scoring catalog IDs, ties broken by catalog ID
+func (s *TrendingService) top(catalogScores map[string]float64) []string {
+	catalogIDs := make([]string, 0, len(catalogScores))
+	for catalogID := range catalogScores {
+		catalogIDs = append(catalogIDs, catalogID)
+	}
+	sort.Slice(catalogIDs, func(i, j int) bool {
+		if catalogScores[catalogIDs[i]] != catalogScores[catalogIDs[j]] {
+			return catalogScores[catalogIDs[i]] > catalogScores[catalogIDs[j]]
+		}
+		return catalogIDs[i] < catalogIDs[j]
+	})
+	if len(catalogIDs) > s.topN {
+		catalogIDs = catalogIDs[:s.topN]
+	}
+	return catalogIDs
+}
+
+// catalogCategories returns the category of each catalog from price_product_info
+func (s *TrendingService) catalogCategories(catalogs map[string]float64) (map[string]string, error) {
+	catalogIDs := make([]string, 0, len(catalogs))
+	for catalogID := range catalogs {
+		catalogIDs = append(catalogIDs, catalogID)
+	}
+
+	categories := make(map[string]string, len(catalogIDs))
+	for start := 0; start < len(catalogIDs); start += 1000 {
+		end := min(start+1000, len(catalogIDs))
+
+		var rows []struct {
+			CatalogID string
+			Category  string
+		}
+		err := s.db.Model(&models.PriceProductInfo{}).
+			Select("catalog_id, MIN(category) AS category").
+			Where("catalog_id IN ?", catalogIDs[start:end]).
+			Group("catalog_id").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to query price_product_info table: %w", err)
+		}
+		for _, row := range rows {
+			categories[row.CatalogID] = row.Category
+		}
+	}
+	return categories, nil
+}
+
+// TrendingCatalogIDs returns a computed trending list with the pins and
+// blocklist applied. An empty scope value selects the global list.
+func (s *TrendingService) TrendingCatalogIDs(scope, value string) ([]string, error) {
+	catalogIDs, err := s.trendingList(scope, value)
+	if err != nil || len(catalogIDs) == 0 {
+		return nil, err
+	}
+	return s.applyOverrides(catalogIDs)
+}
+
+// trendingList returns a computed trending list as stored
+func (s *TrendingService) trendingList(scope, value string) ([]string, error) {
+	var catalogIDs []string
+	err := s.db.Model(&models.TrendingCatalog{}).
+		Where("scope = ? AND scope_value = ?", scope, trendingScopeValue(value)).
+		Order("position").
+		Pluck("catalog_id", &catalogIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return catalogIDs, nil
+}
+
+// FallbackCatalogIDs returns the trending catalogs for a user's city, then
+// those of the categories the user has recently browsed or bought, topped up
+// from the global list, or the cold start snapshot while nothing has been
+// computed, along with the catalog source naming the most specific list used
+func (s *TrendingService) FallbackCatalogIDs(userID, city string) ([]string, string) {
+	var catalogIDs []string
+	source := models.CatalogSourceGlobal
+
+	if city = trendingScopeValue(city); city != "" {
+		cityCatalogIDs, err := s.trendingList(models.TrendingScopeCity, city)
+		if err != nil {
+			fmt.Printf("Warning: Failed to get trending catalogs for %s: %v\n", city, err)
+		} else if len(cityCatalogIDs) > 0 {
+			catalogIDs = cityCatalogIDs
+			source = models.CatalogSourceCityTrending
+		}
+	}
+
+	categories, err := s.userCategories(userID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to get recent categories of user %s: %v\n", userID, err)
+	}
+	for _, category := range categories {
+		categoryCatalogIDs, err := s.trendingList(models.TrendingScopeCategory, category)
+		if err != nil {
+			fmt.Printf("Warning: Failed to get trending catalogs for category %s: %v\n", category, err)
+			continue
+		}
+		if len(categoryCatalogIDs) > 0 && source == models.CatalogSourceGlobal {
+			source = models.CatalogSourceCategoryTrending
+		}
+		catalogIDs = append(catalogIDs, categoryCatalogIDs...)
+	}
+
+	globalCatalogIDs, err := s.trendingList(models.TrendingScopeGlobal, "")
+	if err != nil {
+		fmt.Printf("Warning: Failed to get global trending catalogs: %v\n", err)
+	}
+	catalogIDs = append(catalogIDs, globalCatalogIDs...)
+
+	if len(catalogIDs) == 0 {
+		snapshot, err := LoadTrendingSnapshot()
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		catalogIDs = snapshot
+		source = models.CatalogSourceSnapshot
+	}
+
+	result, err := s.applyOverrides(catalogIDs)
+	if err != nil {
+		fmt.Printf("Warning: Failed to apply catalog overrides: %v\n", err)
+		return catalogIDs, source
+	}
+	return result, source
+}
+
+// userCategories returns the categories of the catalogs a user clicked or
+// ordered within the window, most active first, at most trendingUserCategories
+func (s *TrendingService) userCategories(userID string) ([]string, error) {
+	if userID == "" {
+		return nil, nil
+	}
+	since := time.Now().Add(-s.window)
+
+	var orderCounts []catalogEventCount
+	err := s.db.Table("order_items").
+		Select("order_items.catalog_id AS catalog_id, SUM(order_items.quantity) AS events").
+		Joins("JOIN orders ON orders.order_id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.created_at >= ? AND order_items.catalog_id <> ''", userID, since).
+		Group("order_items.catalog_id").
+		Scan(&orderCounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	var clickCounts []catalogEventCount
+	err = s.db.Model(&models.CatalogClickEvent{}).
+		Select("catalog_id, COUNT(*) AS events").
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Group("catalog_id").
+		Scan(&clickCounts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %w", err)
+	}
+
+	catalogScores := make(map[string]float64)
+	for _, count := range orderCounts {
+		catalogScores[count.CatalogID] += count.Events * trendingOrderWeight
+	}
+	for _, count := range clickCounts {
+		catalogScores[count.CatalogID] += count.Events * trendingClickWeight
+	}
+	if len(catalogScores) == 0 {
+		return nil, nil
+	}
+
+	categories, err := s.catalogCategories(catalogScores)
+	if err != nil {
+		return nil, err
+	}
+	categoryScores := make(map[string]float64)
+	for catalogID, score := range catalogScores {
+		if category := trendingScopeValue(categories[catalogID]); category != "" {
+			categoryScores[category] += score
+		}
+	}
+
+	ranked := make([]string, 0, len(categoryScores))
+	for category := range categoryScores {
+		ranked = append(ranked, category)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if categoryScores[ranked[i]] != categoryScores[ranked[j]] {
+			return categoryScores[ranked[i]] > categoryScores[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	if len(ranked) > trendingUserCategories {
+		ranked = ranked[:trendingUserCategories]
+	}
+	return ranked, nil
+}
+
+// LoadTrendingSnapshot returns the catalog IDs of the cold start snapshot
+func LoadTrendingSnapshot() ([]string, error) {
+	var snapshot TrendingSnapshot
+	if err := json.Unmarshal(trendingSnapshotJSON, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid trending snapshot: %w", err)
+	}
+	return snapshot.CatalogIDs, nil
+}
+
+// applyOverrides puts the pinned catalogs first, in the order they were
+// pinned, and drops blocklisted and repeated catalogs
+func (s *TrendingService) applyOverrides(catalogIDs []string) ([]string, error) {
+	overrides, err := s.ListOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	var pinned []string
+	blocked := make(map[string]bool)
+	for _, override := range overrides {
+		switch override.Action {
+		case models.CatalogOverridePin:
+			pinned = append(pinned, override.CatalogID)
+		case models.CatalogOverrideBlock:
+			blocked[override.CatalogID] = true
+		}
+	}
+
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(pinned)+len(catalogIDs))
+	for _, catalogID := range append(pinned, catalogIDs...) {
+		if seen[catalogID] || blocked[catalogID] {
+			continue
+		}
+		seen[catalogID] = true
+		result = append(result, catalogID)
+	}
+	if len(result) > s.topN {
+		result = result[:s.topN]
+	}
+	return result, nil
+}
+
+// blockedCatalogs returns the blocklisted catalog IDs
+func (s *TrendingService) blockedCatalogs() (map[string]bool, error) {
+	var catalogIDs []string
+	err := s.db.Model(&models.CatalogOverride{}).
+		Where("action = ?", models.CatalogOverrideBlock).
+		Pluck("catalog_id", &catalogIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+
+	blocked := make(map[string]bool, len(catalogIDs))
+	for _, catalogID := range catalogIDs {
+		blocked[catalogID] = true
+	}
+	return blocked, nil
+}
+
+// ListOverrides returns the pinned and blocklisted catalogs, oldest first
+func (s *TrendingService) ListOverrides() ([]models.CatalogOverride, error) {
+	var overrides []models.CatalogOverride
+	if err := s.db.Order("created_at, id").Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return overrides, nil
+}
+
+// SetOverride pins or blocklists a catalog, replacing any earlier override
+func (s *TrendingService) SetOverride(catalogID string, req models.CatalogOverrideRequest, actor string) (*models.CatalogOverride, error) {
+	override := models.CatalogOverride{
+		CatalogID: catalogID,
+		Action:    req.Action,
+		Reason:    req.Reason,
+		UpdatedBy: actor,
+	}
+
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "catalog_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"action", "reason", "updated_by", "updated_at"}),
+	}).Create(&override).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save catalog override: %w", err)
+	}
+
+	if err := s.db.Where("catalog_id = ?", catalogID).First(&override).Error; err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	return &override, nil
+}
+
+// RemoveOverride clears the pin or blocklisting of a catalog
+func (s *TrendingService) RemoveOverride(catalogID string) error {
+	result := s.db.Where("catalog_id = ?", catalogID).Delete(&models.CatalogOverride{})
+	if result.Error != nil {
+		return fmt.Errorf("database error: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no override for catalog %s", catalogID)
+	}
+	return nil
+}
+
+// trendingScopeValue normalises a city or category so that spellings
+// differing only in case share one trending list
+func trendingScopeValue(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
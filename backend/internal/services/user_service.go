@@ -3,11 +3,14 @@ package services
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"meesho-clone/configs"
 	"meesho-clone/internal/models"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserService handles user-related operations
@@ -34,8 +37,15 @@ func (s *UserService) CreateOrGetUser(phoneNumber string) (*models.User, error)
 	var user models.User
 
 	// Check if user already exists
-	err := s.db.Where("phone_number = ?", phoneNumber).First(&user).Error
+	err := wherePhoneNumber(s.db.Unscoped(), phoneNumber).First(&user).Error
 	if err == nil {
+		// Logging in during the deletion grace period restores the account
+		if user.DeletedAt.Valid {
+			if err := s.db.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+				return nil, fmt.Errorf("failed to restore user: %v", err)
+			}
+			user.DeletedAt = gorm.DeletedAt{}
+		}
 		// User exists, return existing user
 		return &user, nil
 	}
@@ -49,6 +59,8 @@ func (s *UserService) CreateOrGetUser(phoneNumber string) (*models.User, error)
 	newUser := models.User{
 		UserID:      s.generateUserID(),
 		PhoneNumber: phoneNumber,
+		PhoneHash:   models.PhoneHashOf(phoneNumber),
+		Role:        models.RoleCustomer,
 		Name:        fmt.Sprintf("User %s", phoneNumber[len(phoneNumber)-4:]), // Use last 4 digits as name
 	}
 
@@ -76,7 +88,7 @@ func (s *UserService) GetUserByID(userID string) (*models.User, error) {
 // GetUserByPhoneNumber retrieves user by phone number
 func (s *UserService) GetUserByPhoneNumber(phoneNumber string) (*models.User, error) {
 	var user models.User
-	err := s.db.Where("phone_number = ?", phoneNumber).First(&user).Error
+	err := wherePhoneNumber(s.db, phoneNumber).First(&user).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, fmt.Errorf("user not found")
@@ -86,11 +98,193 @@ func (s *UserService) GetUserByPhoneNumber(phoneNumber string) (*models.User, er
 	return &user, nil
 }
 
-// UpdateUser updates user information
-func (s *UserService) UpdateUser(userID string, updates map[string]interface{}) error {
-	err := s.db.Model(&models.User{}).Where("user_id = ?", userID).Updates(updates).Error
+// wherePhoneNumber matches users by phone number through the blind index,
+// falling back to the plaintext column for rows not yet migrated by cmd/encrypt-phones
+func wherePhoneNumber(db *gorm.DB, phoneNumber string) *gorm.DB {
+	return db.Where("phone_hash = ? OR (phone_hash IS NULL AND phone_number = ?)", *models.PhoneHashOf(phoneNumber), phoneNumber)
+}
+
+var (
+	// ErrNoProfileChanges is returned when a profile update does not set any field
+	ErrNoProfileChanges = errors.New("no profile fields to update")
+
+	// ErrPhoneNumberInUse is returned when another account already uses the phone number
+	ErrPhoneNumberInUse = errors.New("phone number is already registered to another account")
+
+	// ErrSamePhoneNumber is returned when the new phone number equals the current one
+	ErrSamePhoneNumber = errors.New("new phone number is the same as the current one")
+)
+
+// UpdateProfile applies the fields set in the request to the user's profile
+// and records an audit entry for every field whose value changed
+func (s *UserService) UpdateProfile(userID string, req *models.UpdateProfileRequest, actor string) (*models.User, error) {
+	if req.Name == nil && req.Email == nil && req.Language == nil && req.Gender == nil {
+		return nil, ErrNoProfileChanges
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&user).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("user not found")
+			}
+			return fmt.Errorf("database error: %v", err)
+		}
+
+		updates := map[string]interface{}{}
+		var audits []models.ProfileAudit
+		change := func(field string, current *string, value *string) {
+			if value == nil {
+				return
+			}
+			next := strings.TrimSpace(*value)
+			if field == "email" {
+				next = strings.ToLower(next)
+			}
+			if next == *current {
+				return
+			}
+			audits = append(audits, models.ProfileAudit{
+				UserID:   userID,
+				Field:    field,
+				OldValue: *current,
+				NewValue: next,
+				Actor:    actor,
+			})
+			updates[field] = next
+			*current = next
+		}
+		change("name", &user.Name, req.Name)
+		change("email", &user.Email, req.Email)
+		change("language", &user.Language, req.Language)
+		change("gender", &user.Gender, req.Gender)
+
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.User{}).Where("user_id = ?", userID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update user: %v", err)
+		}
+		if err := tx.Create(&audits).Error; err != nil {
+			return fmt.Errorf("failed to record profile changes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// CheckPhoneNumberAvailable reports whether the user may move to the phone number
+func (s *UserService) CheckPhoneNumberAvailable(userID, phoneNumber string) error {
+	return s.checkPhoneNumberAvailable(s.db, userID, phoneNumber)
+}
+
+// checkPhoneNumberAvailable checks the number against every account, including
+// deleted ones, since idx_phone_number still covers them
+func (s *UserService) checkPhoneNumberAvailable(tx *gorm.DB, userID, phoneNumber string) error {
+	var owner models.User
+	err := wherePhoneNumber(tx.Unscoped(), phoneNumber).First(&owner).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	if owner.UserID == userID {
+		return ErrSamePhoneNumber
+	}
+	return ErrPhoneNumberInUse
+}
+
+// ChangePhoneNumber moves the user to a new phone number. The user ID, and so
+// the user's orders, addresses and mapping, stay the same. The old number is
+// kept in the history and every session is logged out.
+func (s *UserService) ChangePhoneNumber(userID, newPhoneNumber string) (*models.User, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&user).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("user not found")
+			}
+			return fmt.Errorf("database error: %v", err)
+		}
+
+		if err := s.checkPhoneNumberAvailable(tx, userID, newPhoneNumber); err != nil {
+			return err
+		}
+
+		history := models.PhoneNumberHistory{
+			UserID:      userID,
+			PhoneNumber: user.PhoneNumber,
+			ReplacedBy:  newPhoneNumber,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return fmt.Errorf("failed to record phone number history: %w", err)
+		}
+
+		err = tx.Model(&user).Select("phone_number", "phone_hash").Updates(models.User{
+			PhoneNumber: newPhoneNumber,
+			PhoneHash:   models.PhoneHashOf(newPhoneNumber),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to update phone number: %w", err)
+		}
+		user.PhoneNumber = newPhoneNumber
+		user.PhoneHash = models.PhoneHashOf(newPhoneNumber)
+
+		return NewSessionService().revokeAllSessions(tx, userID)
+	})
+	if err != nil {
+		// A concurrent signup may have claimed the number after our check
+		if !errors.Is(err, ErrPhoneNumberInUse) && !errors.Is(err, ErrSamePhoneNumber) &&
+			s.checkPhoneNumberAvailable(s.db, userID, newPhoneNumber) == ErrPhoneNumberInUse {
+			return nil, ErrPhoneNumberInUse
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SetRole changes the role of a user
+func (s *UserService) SetRole(userID, role string) (*models.User, error) {
+	if !models.IsValidRole(role) {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+
+	user, err := s.GetUserByID(userID)
 	if err != nil {
-		return fmt.Errorf("failed to update user: %v", err)
+		return nil, err
+	}
+	if err := s.db.Model(user).Update("role", role).Error; err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	return user, nil
+}
+
+// RecordLookup writes an audit entry for a user lookup. Failures are logged
+// rather than returned so that auditing never blocks the lookup itself.
+func (s *UserService) RecordLookup(entry models.UserLookupAudit) {
+	if err := s.db.Create(&entry).Error; err != nil {
+		fmt.Printf("Warning: Failed to record user lookup: %v\n", err)
+	}
+}
+
+// GetUserCode fetches the user's RTO code from the user_mapping table
+func (s *UserService) GetUserCode(userID string) (string, error) {
+	var userMapping models.UserMapping
+	err := s.db.Where("user_id = ?", userID).First(&userMapping).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to find user mapping for user_id %s: %w", userID, err)
+	}
+
+	if userMapping.Code == "" {
+		return "", fmt.Errorf("no code found for user_id %s", userID)
 	}
-	return nil
+	return userMapping.Code, nil
 }
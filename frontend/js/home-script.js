@@ -154,10 +154,11 @@ class MeeshoHome {
 
         try {
             // Call the homescreen API
-            const response = await fetch(`http://localhost:8080/api/v1/home/?user_id=${this.userData.userId}`, {
+            const response = await fetch('http://localhost:8080/api/v1/home/', {
                 method: 'GET',
                 headers: {
                     'Content-Type': 'application/json',
+                    'Authorization': `Bearer ${this.userData.accessToken}`,
                 }
             });
 
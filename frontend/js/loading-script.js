@@ -55,11 +55,12 @@ class LoadingPage {
         }
 
         try {
-            // Call the catalog API with user ID
-            const response = await fetch(`http://localhost:8080/api/v1/catalog/?user_id=${this.userData.userId}`, {
+            // Call the catalog API as the logged in user
+            const response = await fetch('http://localhost:8080/api/v1/catalog/', {
                 method: 'GET',
                 headers: {
                     'Content-Type': 'application/json',
+                    'Authorization': `Bearer ${this.userData.accessToken}`,
                 }
             });
 
@@ -141,12 +141,12 @@ class ProductDetails {
         
         // Get user data from localStorage
         const userDataString = localStorage.getItem('userData');
-        let userID = 'user123'; // Default fallback
+        let accessToken = '';
         
         if (userDataString) {
             try {
                 const userData = JSON.parse(userDataString);
-                userID = userData.userId || userID;
+                accessToken = userData.accessToken || accessToken;
             } catch (error) {
                 console.error('Error parsing user data:', error);
             }
@@ -157,10 +157,11 @@ class ProductDetails {
             this.showLoading(true);
             
             // Call the product details API
-            const response = await fetch(`http://localhost:8080/api/v1/product/details?product_id=${productId}&user_id=${userID}`, {
+            const response = await fetch(`http://localhost:8080/api/v1/product/details?product_id=${productId}`, {
                 method: 'GET',
                 headers: {
                     'Content-Type': 'application/json',
+                    'Authorization': `Bearer ${accessToken}`,
                 }
             });
 
@@ -491,12 +492,17 @@ class ProductDetails {
                 return;
             }
 
+            const addressId = await this.chooseDeliveryAddress(userData);
+            if (!addressId) {
+                return;
+            }
+
             // Prepare request payload
             const requestPayload = {
-                user_id: userData.userId,
                 product_id: this.productData.id,
                 catalog_id: this.productData.catalogId || '12345',
-                quantity: 1
+                quantity: 1,
+                address_id: addressId
             };
             
             console.log('Request Payload:', requestPayload);
@@ -507,6 +513,7 @@ class ProductDetails {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
+                    'Authorization': `Bearer ${userData.accessToken}`,
                 },
                 body: JSON.stringify(requestPayload)
             });
@@ -539,6 +546,72 @@ class ProductDetails {
         console.log('=== BUY NOW DEBUG END ===');
     }
 
+    async chooseDeliveryAddress(userData) {
+        const addressesUrl = `http://localhost:8080/api/v1/auth/profile/${userData.userId}/addresses`;
+        const headers = {
+            'Content-Type': 'application/json',
+            'Authorization': `Bearer ${userData.accessToken}`,
+        };
+
+        const response = await fetch(addressesUrl, { method: 'GET', headers });
+        const result = await response.json();
+        if (!response.ok || !result.success) {
+            alert(result.error || 'Could not load your addresses. Please try again.');
+            return null;
+        }
+
+        const addresses = result.data || [];
+        if (addresses.length === 0) {
+            return this.addDeliveryAddress(addressesUrl, headers);
+        }
+
+        // Orders go to the default address unless another one is picked
+        const defaultIndex = Math.max(addresses.findIndex(address => address.is_default), 0);
+        const options = addresses.map((address, i) => `${i + 1}. ${address.name}, ${address.line1}, ${address.city} - ${address.pincode}`);
+        options.push(`${addresses.length + 1}. Add a new address`);
+
+        const selected = prompt('Deliver to:\n' + options.join('\n'), String(defaultIndex + 1));
+        if (!selected || isNaN(selected) || selected < 1 || selected > options.length) {
+            return null;
+        }
+        if (Number(selected) === options.length) {
+            return this.addDeliveryAddress(addressesUrl, headers);
+        }
+        return addresses[selected - 1].address_id;
+    }
+
+    async addDeliveryAddress(addressesUrl, headers) {
+        const fields = [
+            ['name', 'Full name'],
+            ['phone', '10-digit mobile number'],
+            ['line1', 'House no., building, street'],
+            ['city', 'City'],
+            ['state', 'State'],
+            ['pincode', '6-digit pincode'],
+        ];
+
+        const address = { is_default: true };
+        for (const [key, label] of fields) {
+            const value = (prompt(`Delivery address - ${label}:`) || '').trim();
+            if (!value) {
+                return null;
+            }
+            address[key] = value;
+        }
+
+        const response = await fetch(addressesUrl, {
+            method: 'POST',
+            headers,
+            body: JSON.stringify(address)
+        });
+        const result = await response.json();
+        if (!response.ok || !result.success) {
+            alert(`Could not save the address: ${result.error || 'Unknown error occurred'}`);
+            return null;
+        }
+        return result.data.address_id;
+    }
+
     generateOrderId() {
         const timestamp = Date.now().toString().slice(-6);
         const random = Math.floor(Math.random() * 1000).toString().padStart(3, '0');
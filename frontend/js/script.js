@@ -72,8 +72,8 @@ continueBtn.addEventListener('click', async function(e) {
     continueBtn.disabled = true;
     
     try {
-        // Call the login API
-        const response = await fetch('http://localhost:8080/api/v1/auth/login', {
+        // Ask the backend to send an OTP to the phone number
+        const otpResponse = await fetch('http://localhost:8080/api/v1/auth/otp/request', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
@@ -82,6 +82,34 @@ continueBtn.addEventListener('click', async function(e) {
                 phone_number: phoneNumber
             })
         });
+
+        const otpResult = await otpResponse.json();
+
+        if (!otpResponse.ok || !otpResult.success) {
+            alert(otpResult.error || 'Could not send OTP. Please try again.');
+            continueBtn.innerHTML = 'Continue';
+            continueBtn.disabled = false;
+            return;
+        }
+
+        const code = (window.prompt('Enter the 6-digit OTP sent to +91 ' + phoneNumber) || '').trim();
+        if (!code) {
+            continueBtn.innerHTML = 'Continue';
+            continueBtn.disabled = false;
+            return;
+        }
+
+        // Verify the OTP to log in
+        const response = await fetch('http://localhost:8080/api/v1/auth/otp/verify', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({
+                phone_number: phoneNumber,
+                code: code
+            })
+        });
         
         const result = await response.json();
         
@@ -91,7 +119,9 @@ continueBtn.addEventListener('click', async function(e) {
                 phone: '+91' + phoneNumber,
                 loginTime: new Date().toISOString(),
                 userId: result.data.user_id,
-                name: result.data.name
+                name: result.data.name,
+                accessToken: result.data.tokens.access_token,
+                refreshToken: result.data.tokens.refresh_token
             };
             
             // Store in localStorage